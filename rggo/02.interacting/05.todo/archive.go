@@ -0,0 +1,129 @@
+package todo
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrNoArchive = errors.New("storage has no archive")
+
+// Storage that keeps archived items apart from the list,
+// so they are not loaded with it
+type ArchiveStorage interface {
+	// Returns the storage of the archived items. It shares the lock of the list.
+	Archive() (Storage, error)
+}
+
+// Removes the items completed more than olderThan ago and returns them.
+// References between the removed items are kept, the rest are dropped.
+func (l *List) Archive(olderThan time.Duration) List {
+	cutoff := time.Now().Add(-olderThan)
+
+	archived := List{}
+	for _, t := range l.Items {
+		if t.Done && !t.CompletedAt.After(cutoff) {
+			archived.Items = append(archived.Items, t.clone())
+		}
+	}
+
+	for _, t := range archived.Items {
+		l.Delete(t.ID)
+	}
+
+	return archived
+}
+
+// Moves the items completed more than olderThan ago to the archive of the storage
+// and returns their number. The items get new IDs in the archive.
+// Archiving is not recorded in the history.
+func Archive(s Storage, olderThan time.Duration) (int, error) {
+	n := 0
+	err := withArchive(s, true, func(l, archive *List) error {
+		archived := l.Archive(olderThan)
+		archive.appendRenumbered(archived.Items)
+		n = len(archived.Items)
+		return nil
+	})
+
+	return n, err
+}
+
+// Moves the archived item back to the end of the list and returns its new ID
+func Restore(s Storage, id int) (int, error) {
+	newID := 0
+	err := withArchive(s, false, func(l, archive *List) error {
+		idx, err := archive.Index(id)
+		if err != nil {
+			return err
+		}
+
+		t := archive.Items[idx].clone()
+		if err := archive.Delete(id); err != nil {
+			return err
+		}
+
+		l.appendRenumbered([]item{t})
+		newID = l.Items[len(l.Items)-1].ID
+		return nil
+	})
+
+	return newID, err
+}
+
+// Deletes all archived items and returns their number
+func Purge(s Storage) (int, error) {
+	n := 0
+	err := withArchive(s, true, func(l, archive *List) error {
+		n = len(archive.Items)
+		archive.Items = nil
+		return nil
+	})
+
+	return n, err
+}
+
+// Loads the list and its archive while the storage is locked, applies fn and saves both.
+// The archive is saved first when items go to it and last when they come back,
+// so a failure never loses an item.
+func withArchive(s Storage, toArchive bool, fn func(l, archive *List) error) error {
+	as, ok := s.(ArchiveStorage)
+	if !ok {
+		return ErrNoArchive
+	}
+
+	a, err := as.Archive()
+	if err != nil {
+		return err
+	}
+
+	if lk, ok := s.(Locker); ok {
+		if err := lk.Lock(); err != nil {
+			return err
+		}
+		defer lk.Unlock()
+	}
+
+	l, archive := &List{}, &List{}
+	if err := s.Load(l); err != nil {
+		return err
+	}
+	if err := a.Load(archive); err != nil {
+		return err
+	}
+
+	if err := fn(l, archive); err != nil {
+		return err
+	}
+
+	if toArchive {
+		if err := a.Save(archive); err != nil {
+			return err
+		}
+		return s.Save(l)
+	}
+
+	if err := s.Save(l); err != nil {
+		return err
+	}
+	return a.Save(archive)
+}
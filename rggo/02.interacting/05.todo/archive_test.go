@@ -0,0 +1,45 @@
+package todo_test
+
+import (
+	"testing"
+	"time"
+
+	"rggo/interacting/todo"
+)
+
+// Tests that only items completed long enough ago are archived
+func TestListArchive(t *testing.T) {
+	// Arrange
+	l := todo.List{}
+	l.Add("Old done")
+	l.Add("Open")
+	l.Add("Recently done")
+	l.Add("Old subtask")
+	l.SetParent(4, 1)
+	l.Block(2, 1)
+	l.Complete(4)
+	l.Complete(1)
+	l.Complete(3)
+
+	old := time.Now().Add(-48 * time.Hour)
+	l.Items[0].CompletedAt = old
+	l.Items[3].CompletedAt = old
+
+	// Act
+	archived := l.Archive(24 * time.Hour)
+
+	// Assert
+	if len(archived.Items) != 2 || archived.Items[0].Task != "Old done" || archived.Items[1].Task != "Old subtask" {
+		t.Fatalf("Expected old completed items to be archived, got %v.", archived)
+	}
+	if archived.Items[1].Parent != 1 {
+		t.Errorf("Expected archived subtask to keep its parent, got %d.", archived.Items[1].Parent)
+	}
+
+	if len(l.Items) != 2 || l.Items[0].Task != "Open" || l.Items[1].Task != "Recently done" {
+		t.Fatalf("Expected open and recent items to stay, got %v.", l)
+	}
+	if len(l.Items[0].BlockedBy) != 0 {
+		t.Errorf("Expected blocker to be dropped, got %v.", l.Items[0].BlockedBy)
+	}
+}
@@ -3,6 +3,12 @@ package globals
 const (
 	// Environment variable for overriding the file name to store todo list
 	EnvironmentVariable = "TODO_FILENAME"
+	// Environment variable with the passphrase encrypting the todo list
+	KeyVariable = "TODO_KEY"
+	// Environment variable with the name of a file holding the passphrase
+	KeyFileVariable = "TODO_KEY_FILE"
+	// Environment variable with the bearer token sent to the todoServer on sync
+	TokenVariable = "TODO_TOKEN"
 	// Test file name
 	TestFileName = ".test_todo.json"
 )
@@ -0,0 +1,26 @@
+//go:build !inmemory && !containers
+// +build !inmemory,!containers
+
+package main
+
+import (
+	"os"
+
+	"rggo/interacting/todo"
+	"rggo/interactiveTools/pomo/pomodoro/repository"
+)
+
+// Reads the focus time of the items from the pomo database
+func loadFocus(l *todo.List, project, dbfile string) error {
+	// Opening a missing database would create it
+	if _, err := os.Stat(dbfile); err != nil {
+		return err
+	}
+
+	repo, err := repository.NewSQLite3Repo(dbfile)
+	if err != nil {
+		return err
+	}
+
+	return l.LoadFocus(project, repo)
+}
@@ -0,0 +1,15 @@
+//go:build inmemory || containers
+// +build inmemory containers
+
+package main
+
+import (
+	"errors"
+
+	"rggo/interacting/todo"
+)
+
+// pomo keeps its intervals in memory in these builds
+func loadFocus(l *todo.List, project, dbfile string) error {
+	return errors.New("focus time is not available in this build")
+}
@@ -0,0 +1,83 @@
+//go:build !inmemory && !containers
+// +build !inmemory,!containers
+
+package main_test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	globals "rggo/interacting/todo/cmd"
+	"rggo/interactiveTools/pomo/pomodoro"
+	"rggo/interactiveTools/pomo/pomodoro/repository"
+)
+
+func TestTodoCLIFocus(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	tempDir := t.TempDir()
+	env := fmt.Sprintf("%s=%s",
+		globals.EnvironmentVariable, filepath.Join(tempDir, "todo.json"))
+
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command(cmdPath, args...)
+		cmd.Env = append(os.Environ(), env)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		return string(out)
+	}
+
+	// Pomodoros spent on the items, as pomo -t records them
+	pomoDB := filepath.Join(tempDir, "pomo.db")
+	repo, err := repository.NewSQLite3Repo(pomoDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []pomodoro.Interval{
+		{Category: pomodoro.CategoryPomodoro, Task: "1", ActualDuration: 50 * time.Minute},
+		{Category: pomodoro.CategoryPomodoro, Task: "1", ActualDuration: 25 * time.Minute},
+		{Category: pomodoro.CategoryPomodoro, Task: "work/1", ActualDuration: 25 * time.Minute},
+	} {
+		i.StartTime = time.Now()
+		if _, err := repo.Create(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("-add", "write report")
+	run("-add", "-project", "work", "review PR")
+
+	t.Run("Default", func(t *testing.T) {
+		out := run("-list", "-v", "-pomo-db", pomoDB)
+		if !strings.HasSuffix(out, "    Focused: 1h15m\n") {
+			t.Errorf("Expected the focus time of the item, got %q.", out)
+		}
+	})
+
+	t.Run("Project", func(t *testing.T) {
+		out := run("-list", "-v", "-project", "work", "-pomo-db", pomoDB)
+		if !strings.HasSuffix(out, "    Focused: 25m\n") {
+			t.Errorf("Expected the focus time of the item, got %q.", out)
+		}
+	})
+
+	t.Run("MissingDatabase", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-list", "-v", "-pomo-db", filepath.Join(tempDir, "missing.db"))
+		cmd.Env = append(os.Environ(), env)
+		if err := cmd.Run(); err == nil {
+			t.Fatal("Expected error for a missing database, got no error.")
+		}
+	})
+}
@@ -1,19 +1,31 @@
 package main
 
 import (
-	"bufio"   // Read data from STDIN input stream (os.Stdin)
-	"flag"    // To process input
-	"fmt"     // To process output
-	"io"      // To use io.Reader interface
-	"os"      // To verify the arguments from cli
-	"strings" // To use Join() to compose a task name
+	"bufio"     // Read data from STDIN input stream (os.Stdin)
+	"errors"    // To detect an encrypted todo file
+	"flag"      // To process input
+	"fmt"       // To process output
+	"io"        // To use io.Reader interface
+	"net/http"  // To send the token of the sync with its client
+	"os"        // To verify the arguments from cli
+	"os/signal" // To stop -remind-every on interrupt
+	"regexp"    // To match tasks with -search -regex
+	"sort"      // To sort IDs of batch operations
+	"strconv"   // To convert the position argument to a number
+	"strings"   // To use Join() to compose a task name
+	"syscall"   // To stop -remind-every on SIGTERM
 	"time"
 
 	"rggo/interacting/todo"
 	globals "rggo/interacting/todo/cmd"
+	"rggo/interacting/todo/storage"
+	"rggo/interacting/todo/tui"
+
+	"github.com/mum4k/termdash/terminal/tcell"
+	"golang.org/x/term"
 )
 
-// Default file name
+// Default storage: a JSON file name or a URI like sqlite:///path/todo.db
 var todoFileName = ".todo.json"
 
 func main() {
@@ -25,37 +37,160 @@ func main() {
 	list := flag.Bool("list", false, "List all tasks")
 	listv := flag.Bool("v", false, "Verbose list tasks")
 	listc := flag.Bool("c", false, "List tasks without completed")
-	complete := flag.Int("complete", 0, "Item to be completed")
-	delete := flag.Int("del", 0, "Item to be deleted")
+	complete := flag.String("complete", "", "IDs of the items to be completed like 1,3,5-8")
+	delete := flag.String("del", "", "IDs of the items to be deleted like 1,3,5-8")
+	delCompleted := flag.Bool("del-completed", false, "Delete all completed items")
+	priority := flag.String("priority", "", "Priority of the task to add or to list (high, medium, low)")
+	due := flag.String("due", "", "Due date of the task to add (YYYY-MM-DD)")
+	tag := flag.String("tag", "", "Comma-separated tags of the task to add or a tag to list")
+	dueBefore := flag.String("due-before", "", "List tasks due before the date (YYYY-MM-DD)")
+	listd := flag.Bool("done", false, "List only completed tasks")
+	search := flag.String("search", "", "List tasks containing the text")
+	regex := flag.Bool("regex", false, "Treat the -search text as a regular expression")
+	since := flag.String("since", "", "List tasks created on or after the date (YYYY-MM-DD)")
+	until := flag.String("until", "", "List tasks created on or before the date (YYYY-MM-DD)")
+	format := flag.String("format", "text", "Format of the listed tasks: text, table, json, template=<go text/template>")
+	sortBy := flag.String("sort", "", "Sort listed tasks by: created, completed, alpha")
+	repeat := flag.String("repeat", "", "Recurrence of the task to add: daily, weekly[:mon,thu], monthly[:day], every:N (days)")
+	parent := flag.Int("parent", 0, "ID of the parent of the task to add")
+	blockedBy := flag.String("blocked-by", "", "Comma-separated IDs of the items blocking the task to add")
+	force := flag.Bool("force", false, "Complete the item even if it has open subtasks")
+	edit := flag.Int("edit", 0, "ID of the item to rename. The new task name is read from arguments or STDIN")
+	reopen := flag.Int("reopen", 0, "ID of the completed item to reopen")
+	note := flag.Int("note", 0, "ID of the item to annotate. The notes are read from arguments or STDIN")
+	attach := flag.Int("attach", 0, "ID of the item to attach the file given as the first argument to")
+	move := flag.Int("move", 0, "ID of the item to move. The new position is the first argument")
+	export := flag.String("export", "", "Write the todo list to STDOUT in the format: md, csv, todo.txt")
+	importFile := flag.String("import", "", "Add tasks from the file. Format by extension: .md, .csv, .txt")
+	undo := flag.Bool("undo", false, "Undo the last change of the todo list")
+	redo := flag.Bool("redo", false, "Redo the last undone change of the todo list")
+	project := flag.String("project", todo.DefaultProject, "Name of the todo list to use")
+	projects := flag.Bool("projects", false, "List the projects with the number of tasks")
+	toProject := flag.String("to-project", "", "Move the -move item to the end of the named project")
+	archive := flag.String("archive", "", "Archive tasks completed before the age like 30d or 12h")
+	archived := flag.Bool("archived", false, "List or export archived tasks instead of the todo list")
+	restore := flag.Int("restore", 0, "ID of the archived item to put back to the todo list")
+	purge := flag.Bool("purge", false, "Delete all archived tasks")
+	interactive := flag.Bool("tui", false, "Open the full-screen interface")
+	remind := flag.Bool("remind", false, "Notify about tasks due soon or overdue")
+	remindSoon := flag.Duration("remind-soon", 24*time.Hour, "How long before the due date -remind notifies")
+	remindEvery := flag.Duration("remind-every", 0, "Keep running -remind at the interval until interrupted")
+	syncList := flag.Bool("sync", false, "Sync the todo list with the -remote todoServer")
+	remoteURL := flag.String("remote", "", "URL of the todoServer to sync with like http://host:8080. Servers requiring authentication get the token of "+globals.TokenVariable)
+	pomoDB := flag.String("pomo-db", "", "pomo database to show the focus time of the items from with -list -v")
+	encrypt := flag.Bool("encrypt", false, "Encrypt the plain todo files with the key of "+globals.KeyVariable+" or "+globals.KeyFileVariable)
 	flag.Parse()
 
-	l := &todo.List{}
+	if *archived {
+		if err := checkArchivedFlags(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
 
-	// Read todo items from file
-	if err := l.Get(todoFileName); err != nil {
+	if *encrypt {
+		n, err := encryptStorage(todoFileName)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Encrypted %d files\n", n)
+		return
+	}
+
+	projectStore, err := openStorage(todoFileName)
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 
-	switch {
-	case (*list && *listv && *listc):
-		fmt.Print(l.Print(true, true))
+	store, err := projectStore.Project(*project)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
-	case *list && *listv:
-		fmt.Print(l.Print(true, false))
+	// Archived items can be listed and exported as the list
+	view := store
+	if *archived {
+		if view, err = archiveOf(store); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
 
-	case *list && *listc:
-		fmt.Print(l.Print(false, true))
+	l := &todo.List{}
 
+	// Read todo items from the storage
+	if err := view.Load(l); err != nil {
+		if errors.Is(err, todo.ErrNotEncrypted) {
+			err = fmt.Errorf("%w: run with -encrypt to encrypt it", err)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	p, err := todo.ParsePriority(*priority)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	switch {
 	case *list:
-		fmt.Print(l.Print(false, false))
+		filter, err := getFilter(&listOptions{
+			priority:         p,
+			tag:              *tag,
+			dueBefore:        *dueBefore,
+			search:           *search,
+			regex:            *regex,
+			since:            *since,
+			until:            *until,
+			sort:             *sortBy,
+			excludeCompleted: *listc,
+			onlyCompleted:    *listd,
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		if *pomoDB != "" {
+			if err := loadFocus(l, *project, *pomoDB); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
 
-	case *complete > 0:
-		if err := l.Complete(*complete); err != nil {
+		selected := l.Select(filter)
+		if err := selected.Write(os.Stdout, *format, *listv); err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
-		saveListToFile(l)
+
+	case *complete != "":
+		n := 0
+		updateList(store, func(l *todo.List) error {
+			// Ranges select the items of the locked list
+			ids, err := parseIDRanges(*complete, l)
+			if err != nil {
+				return err
+			}
+
+			open := countOpen(l, ids)
+			defer func() { n = open - countOpen(l, ids) }()
+
+			if !*force {
+				return l.CompleteMany(ids)
+			}
+			for _, id := range ids {
+				if err := l.ForceComplete(id); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		fmt.Printf("Completed %d tasks\n", n)
 
 	case *add:
 		tasks, err := getTask(os.Stdin, flag.Args()...)
@@ -64,18 +199,210 @@ func main() {
 			os.Exit(1)
 		}
 
-		for _, task := range tasks {
-			l.Add(task)
+		opts, err := getOptions(p, *due, *tag, *repeat)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		blockers, err := parseIDs(*blockedBy)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		updateList(store, func(l *todo.List) error {
+			for _, task := range tasks {
+				l.Add(task, opts...)
+				if err := linkTask(l, l.Items[len(l.Items)-1].ID, *parent, blockers); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	case *delete != "":
+		n := 0
+		updateList(store, func(l *todo.List) error {
+			ids, err := parseIDRanges(*delete, l)
+			if err != nil {
+				return err
+			}
+
+			before := len(l.Items)
+			defer func() { n = before - len(l.Items) }()
+
+			return l.DeleteMany(ids)
+		})
+		fmt.Printf("Deleted %d tasks\n", n)
+
+	case *delCompleted:
+		n := 0
+		updateList(store, func(l *todo.List) error {
+			done := l.Select(todo.Filter{Status: todo.StatusDone})
+			n = len(done.Items)
+			for _, t := range done.Items {
+				if err := l.Delete(t.ID); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		fmt.Printf("Deleted %d tasks\n", n)
+
+	case *edit > 0:
+		tasks, err := getTask(os.Stdin, flag.Args()...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		if len(tasks) != 1 {
+			fmt.Fprintln(os.Stderr, "Expected a single new task name")
+			os.Exit(1)
+		}
+
+		updateList(store, func(l *todo.List) error {
+			return l.Edit(*edit, tasks[0])
+		})
+
+	case *note > 0:
+		notes, err := getNotes(os.Stdin, flag.Args()...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		updateList(store, func(l *todo.List) error {
+			return l.SetNotes(*note, notes)
+		})
+
+	case *attach > 0:
+		if flag.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "Expected the file to attach as argument")
+			os.Exit(1)
+		}
+
+		a, err := todo.NewAttachment(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		updateList(store, func(l *todo.List) error {
+			return l.Attach(*attach, a)
+		})
+
+	case *reopen > 0:
+		updateList(store, func(l *todo.List) error {
+			return l.Reopen(*reopen)
+		})
+
+	case *move > 0 && *toProject != "":
+		id, err := todo.MoveToProject(projectStore, *move, *project, *toProject)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Moved to %s as %d\n", *toProject, id)
+
+	case *move > 0:
+		if flag.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "Expected the new position of the item")
+			os.Exit(1)
+		}
+
+		pos, err := strconv.Atoi(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
 
-		saveListToFile(l)
+		updateList(store, func(l *todo.List) error {
+			return l.Move(*move, pos)
+		})
 
-	case *delete > 0:
-		if err := l.Delete(*delete); err != nil {
+	case *export != "":
+		if err := l.Export(os.Stdout, *export); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+	case *importFile != "":
+		if err := importTasks(store, *importFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+	case *archive != "":
+		age, err := parseAge(*archive)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		n, err := todo.Archive(store, age)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Archived %d tasks\n", n)
+
+	case *restore > 0:
+		id, err := todo.Restore(store, *restore)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Restored as %d\n", id)
+
+	case *purge:
+		n, err := todo.Purge(store)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Purged %d tasks\n", n)
+
+	case *interactive:
+		if err := runTUI(store); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+	case *remind:
+		if err := runReminders(store, *remindSoon, *remindEvery); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+	case *syncList:
+		if *remoteURL == "" {
+			fmt.Fprintln(os.Stderr, "Missing -remote URL")
+			os.Exit(1)
+		}
+		if err := syncWith(store, *remoteURL, *project); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+	case *projects:
+		if err := printProjects(projectStore); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+	case *undo:
+		if err := todo.Undo(store); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+	case *redo:
+		if err := todo.Redo(store); err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
-		saveListToFile(l)
 
 	default:
 		fmt.Fprintln(os.Stderr, "Invalid option")
@@ -89,6 +416,71 @@ func checkEnvironment() {
 	}
 }
 
+// Open the storage encrypted with the key given by the environment.
+// Without a key, the passphrase of an encrypted file is asked on the terminal.
+func openStorage(uri string) (todo.ProjectStorage, error) {
+	c, err := envCipher()
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		return storage.New(uri, storage.WithCipher(c))
+	}
+
+	s, err := storage.New(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	// Encrypted files are detected by their header
+	if _, err := s.Projects(); !errors.Is(err, todo.ErrEncrypted) {
+		return s, nil
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, fmt.Errorf("%w: set %s or %s", todo.ErrEncrypted, globals.KeyVariable, globals.KeyFileVariable)
+	}
+
+	fmt.Fprint(os.Stderr, "Passphrase: ")
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	if c, err = todo.NewCipher(passphrase); err != nil {
+		return nil, err
+	}
+
+	return storage.New(uri, storage.WithCipher(c))
+}
+
+// Encrypt the plain files of the storage with the key given by the environment
+func encryptStorage(uri string) (int, error) {
+	c, err := envCipher()
+	if err != nil {
+		return 0, err
+	}
+	if c == nil {
+		return 0, fmt.Errorf("%w: set %s or %s", todo.ErrEmptyKey, globals.KeyVariable, globals.KeyFileVariable)
+	}
+
+	return storage.Encrypt(uri, c)
+}
+
+// Cipher of the passphrase or the key file given by the environment, if any
+func envCipher() (*todo.Cipher, error) {
+	if key := os.Getenv(globals.KeyVariable); key != "" {
+		return todo.NewCipher([]byte(key))
+	}
+
+	if keyFile := os.Getenv(globals.KeyFileVariable); keyFile != "" {
+		return todo.NewCipherFromFile(keyFile)
+	}
+
+	return nil, nil
+}
+
 func redefineFlagUsage() {
 	appName := strings.Trim(os.Args[0], "./")
 	year := time.Now().Year()
@@ -100,14 +492,397 @@ func redefineFlagUsage() {
 	}
 }
 
-// Save the list to the file
-func saveListToFile(l *todo.List) {
-	if err := l.Save(todoFileName); err != nil {
+// Apply changes to the list and save it while the storage is locked
+func updateList(store todo.Storage, fn func(l *todo.List) error) {
+	if err := todo.Update(store, fn); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
+// Print the projects of the storage with the number of open and all tasks
+func printProjects(s todo.ProjectStorage) error {
+	names, err := s.Projects()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		ps, err := s.Project(name)
+		if err != nil {
+			return err
+		}
+
+		l := todo.List{}
+		if err := ps.Load(&l); err != nil {
+			return err
+		}
+
+		open := len(l.Select(todo.Filter{Status: todo.StatusOpen}).Items)
+		fmt.Printf("%s: %d open of %d\n", name, open, len(l.Items))
+	}
+
+	return nil
+}
+
+// Sync the list with the remote list and print the changes and the conflicts.
+// Conflicting items keep their local version until they are changed again.
+func syncWith(store todo.Storage, url, project string) error {
+	var client *http.Client
+	if token := os.Getenv(globals.TokenVariable); token != "" {
+		client = storage.NewTokenClient(token)
+	}
+
+	remote := storage.NewRemote(url, project, client)
+	result, err := todo.Sync(store, remote, remote.String())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Pulled %d, pushed %d, %d conflicts\n", result.Pulled, result.Pushed, len(result.Conflicts))
+	for _, c := range result.Conflicts {
+		fmt.Printf("Conflict: %s\n", c)
+	}
+
+	return nil
+}
+
+// Run the full-screen interface on the terminal
+func runTUI(store todo.Storage) error {
+	term, err := tcell.New()
+	if err != nil {
+		return err
+	}
+	defer term.Close()
+
+	app, err := tui.New(store, term)
+	if err != nil {
+		return err
+	}
+
+	return app.Run()
+}
+
+// Send reminders once or at every interval until interrupted
+func runReminders(store todo.Storage, soon, every time.Duration) error {
+	if err := sendReminders(store, soon); err != nil || every <= 0 {
+		return err
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := sendReminders(store, soon); err != nil {
+				return err
+			}
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// Notify about the tasks that became more urgent and print the reminders.
+// Reminders that could not be sent are tried again next time.
+func sendReminders(store todo.Storage, soon time.Duration) error {
+	return todo.Update(store, func(l *todo.List) error {
+		for _, r := range l.Reminders(time.Now(), soon) {
+			if err := sendNotification(r); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				continue
+			}
+
+			fmt.Println(r)
+			if err := l.MarkReminded(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get the storage of the archived items
+func archiveOf(s todo.Storage) (todo.Storage, error) {
+	as, ok := s.(todo.ArchiveStorage)
+	if !ok {
+		return nil, todo.ErrNoArchive
+	}
+
+	return as.Archive()
+}
+
+// Flags that only read the list, so they can be used with -archived
+var archivedFlags = map[string]bool{
+	"archived": true, "list": true, "v": true, "c": true, "done": true,
+	"priority": true, "tag": true, "due-before": true, "search": true, "regex": true,
+	"since": true, "until": true, "format": true, "sort": true, "export": true,
+	"project": true, "pomo-db": true,
+}
+
+// Returns an error for the flags changing the list, which -archived does not apply to
+func checkArchivedFlags() error {
+	var err error
+	flag.Visit(func(f *flag.Flag) {
+		if err == nil && !archivedFlags[f.Name] {
+			err = fmt.Errorf("-%s cannot be used with -archived", f.Name)
+		}
+	})
+
+	return err
+}
+
+// Parse a positive age like 30d in days or any duration like 12h
+func parseAge(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		n, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("Invalid age %q: must be a positive number of days", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid age %q: %w", s, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("Invalid age %q: must be positive", s)
+	}
+
+	return d, nil
+}
+
+// Get priority, due date, tags and recurrence for a new task
+func getOptions(p todo.Priority, due, tags, repeat string) ([]todo.Option, error) {
+	opts := []todo.Option{todo.WithPriority(p)}
+
+	if due != "" {
+		d, err := parseDate(due)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, todo.WithDue(d))
+	}
+
+	if tags != "" {
+		opts = append(opts, todo.WithTags(strings.Split(tags, ",")...))
+	}
+
+	if repeat != "" {
+		r, err := todo.ParseRecurrence(repeat)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, todo.WithRepeat(r))
+	}
+
+	return opts, nil
+}
+
+// Options of the -list command
+type listOptions struct {
+	priority         todo.Priority
+	tag              string
+	dueBefore        string
+	search           string
+	regex            bool
+	since            string
+	until            string
+	sort             string
+	excludeCompleted bool
+	onlyCompleted    bool
+}
+
+// Get filter to list only tasks matching the options
+func getFilter(o *listOptions) (todo.Filter, error) {
+	filter := todo.Filter{
+		Tag:      o.tag,
+		Priority: o.priority,
+	}
+
+	var err error
+	if filter.Sort, err = todo.ParseSort(o.sort); err != nil {
+		return filter, err
+	}
+
+	switch {
+	case o.excludeCompleted && o.onlyCompleted:
+		return filter, fmt.Errorf("Options -c and -done cannot be used together")
+	case o.excludeCompleted:
+		filter.Status = todo.StatusOpen
+	case o.onlyCompleted:
+		filter.Status = todo.StatusDone
+	}
+
+	if o.regex {
+		if filter.Regexp, err = regexp.Compile(o.search); err != nil {
+			return filter, err
+		}
+	} else {
+		filter.Search = o.search
+	}
+
+	if o.dueBefore != "" {
+		if filter.DueBefore, err = parseDate(o.dueBefore); err != nil {
+			return filter, err
+		}
+	}
+
+	if o.since != "" {
+		if filter.CreatedSince, err = parseDate(o.since); err != nil {
+			return filter, err
+		}
+	}
+
+	// Until includes the whole day
+	if o.until != "" {
+		d, err := parseDate(o.until)
+		if err != nil {
+			return filter, err
+		}
+		filter.CreatedUntil = d.AddDate(0, 0, 1)
+	}
+
+	return filter, nil
+}
+
+// Add tasks from a Markdown, CSV or todo.txt file
+func importTasks(store todo.Storage, filename string) error {
+	format, err := todo.FormatFromFilename(filename)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return todo.Update(store, func(l *todo.List) error {
+		return l.Import(f, format)
+	})
+}
+
+// Parse comma-separated item IDs
+func parseIDs(s string) ([]int, error) {
+	ids := []int{}
+	if s == "" {
+		return ids, nil
+	}
+
+	for _, f := range strings.Split(s, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ID %q: %w", f, err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// Parse comma-separated item IDs and ranges like 1,3,5-8 of the list.
+// Ranges give the IDs of the items in the list, so they may have gaps
+// and can end past the last item. Single IDs must be in the list, and
+// all missing ones are reported. The IDs are sorted and repeated ones are dropped.
+func parseIDRanges(s string, l *todo.List) ([]int, error) {
+	selected := map[int]bool{}
+	missing := []string{}
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+
+		begin, end, isRange := strings.Cut(f, "-")
+		if !isRange {
+			end = begin
+		}
+
+		first, err := strconv.Atoi(begin)
+		if err != nil || first < 1 {
+			return nil, fmt.Errorf("Invalid ID %q", f)
+		}
+
+		last, err := strconv.Atoi(end)
+		if err != nil || last < 1 {
+			return nil, fmt.Errorf("Invalid ID %q", f)
+		}
+
+		if isRange && first > last {
+			return nil, fmt.Errorf("Invalid ID range %d-%d", first, last)
+		}
+
+		if !isRange {
+			if _, err := l.Index(first); err != nil {
+				missing = append(missing, f)
+				continue
+			}
+		}
+
+		for _, t := range l.Items {
+			if t.ID >= first && t.ID <= last {
+				selected[t.ID] = true
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: IDs %s", todo.ErrItemNotFound, strings.Join(missing, ", "))
+	}
+
+	ids := make([]int, 0, len(selected))
+	for id := range selected {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	return ids, nil
+}
+
+// Count the items with the IDs that are not completed
+func countOpen(l *todo.List, ids []int) int {
+	n := 0
+	for _, id := range ids {
+		if idx, err := l.Index(id); err == nil && !l.Items[idx].Done {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Set the parent and the blockers of a new task
+func linkTask(l *todo.List, id, parent int, blockers []int) error {
+	if parent > 0 {
+		if err := l.SetParent(id, parent); err != nil {
+			return err
+		}
+	}
+
+	for _, b := range blockers {
+		if err := l.Block(id, b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Parse date in the local time zone
+func parseDate(s string) (time.Time, error) {
+	d, err := time.ParseInLocation(todo.DateFormat, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid date %q: %w", s, err)
+	}
+
+	return d, nil
+}
+
 // Get description for a new task. From arguments or STDIN
 func getTask(r io.Reader, args ...string) ([]string, error) {
 	// Read arguments from cmd
@@ -135,3 +910,18 @@ func getTask(r io.Reader, args ...string) ([]string, error) {
 
 	return lines, nil
 }
+
+// Read the notes from the arguments or from all of STDIN,
+// so they can have paragraphs separated by empty lines
+func getNotes(r io.Reader, args ...string) (string, error) {
+	if len(args) > 0 {
+		return strings.Join(args, " "), nil
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(data), "\n"), nil
+}
@@ -3,14 +3,18 @@ package main_test
 // import . "main"
 
 import (
-	"fmt"           // To print formatted output
-	"io"            // To use io.WriteString()
-	"os"            // To use operating system types
-	"os/exec"       // To execute external commands
-	"path/filepath" // To deal with directory paths
-	"runtime"       // To identify the running operating system
+	"fmt"               // To print formatted output
+	"io"                // To use io.WriteString()
+	"net/http"          // To serve the list to -sync
+	"net/http/httptest" // To run a test server for -sync
+	"os"                // To use operating system types
+	"os/exec"           // To execute external commands
+	"path/filepath"     // To deal with directory paths
+	"runtime"           // To identify the running operating system
 	"strings"
+	"sync"    // To wait for concurrent commands
 	"testing" // To access testing tools
+	"time"    // To compute due dates
 
 	globals "rggo/interacting/todo/cmd"
 )
@@ -38,7 +42,8 @@ func TestMain(m *testing.M) {
 		binName += ".exe"
 	}
 
-	build := exec.Command("go", "build", "-o", binName)
+	// Reminders are printed without desktop notifications
+	build := exec.Command("go", "build", "-tags", "disable_notification", "-o", binName)
 	if err := build.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Cannot build tool %s: %s", binName, err)
 		os.Exit(1)
@@ -59,6 +64,8 @@ func TestMain(m *testing.M) {
 	fmt.Println("Cleaning up...")
 	os.Remove(binName)
 	os.Remove(globals.TestFileName)
+	os.Remove(globals.TestFileName + ".lock")
+	os.Remove(globals.TestFileName + ".history")
 
 	if env != "" {
 		os.Setenv(globals.EnvironmentVariable, env)
@@ -69,6 +76,33 @@ func TestMain(m *testing.M) {
 	os.Exit(result)
 }
 
+// Command running the tool with the todo file in the directory
+func cliCommand(t *testing.T, dir string, args ...string) *exec.Cmd {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(filepath.Join(wd, binName), args...)
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("%s=%s", globals.EnvironmentVariable, filepath.Join(dir, "todo.json")))
+	return cmd
+}
+
+// Run the tool with the todo file in the directory and return its output.
+// The test fails if the tool fails.
+func runCLI(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	out, err := cliCommand(t, dir, args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s: %s", err, out)
+	}
+	return string(out)
+}
+
 // Execute tests that depend on each other
 func TestTodoCLI(t *testing.T) {
 	task := "test task number 1"
@@ -145,7 +179,7 @@ func TestTodoCLI(t *testing.T) {
 
 		out := listCommand(cmdPath, t)
 		expected := fmt.Sprintf(
-			"X 1: %s        \n",
+			"X 2: %s        \n",
 			task2)
 
 		actual := string(out)
@@ -171,9 +205,9 @@ func TestTodoCLI(t *testing.T) {
 
 		out := listCommand(cmdPath, t)
 		expected := fmt.Sprintf(
-			"X 1: %s        \n"+
-				"  2: %s        \n"+
-				"  3: %s        \n",
+			"X 2: %s        \n"+
+				"  3: %s        \n"+
+				"  4: %s        \n",
 			task2, task3, task4)
 
 		actual := string(out)
@@ -181,6 +215,679 @@ func TestTodoCLI(t *testing.T) {
 	})
 }
 
+// Execute tests for the task details: priority, due date and tags
+func TestTodoCLIDetails(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	os.Remove(globals.TestFileName)
+	defer os.Remove(globals.TestFileName)
+
+	t.Run("AddTasksWithDetails", func(t *testing.T) {
+		tasks := [][]string{
+			{"-add", "-priority", "high", "-due", "2026-10-25", "-tag", "ops,infra", "rotate keys"},
+			{"-add", "-tag", "ops", "-due", "2026-12-01", "renew certs"},
+			{"-add", "-tag", "home", "water plants"},
+		}
+		for _, args := range tasks {
+			cmd := exec.Command(cmdPath, args...)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("%s: %s", err, out)
+			}
+		}
+	})
+
+	t.Run("ListFilteredTasks", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-list", "-tag", "ops", "-due-before", "2026-11-01")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "  1: rotate keys (high) due 2026-10-25 #ops #infra        \n"
+		assertString(expected, string(out), t)
+	})
+
+	t.Run("InvalidPriority", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-add", "-priority", "urgent", "task")
+		if err := cmd.Run(); err == nil {
+			t.Fatal("Expected error for invalid priority, got no error.")
+		}
+	})
+}
+
+// Execute tests for edit, reopen and move of the tasks
+func TestTodoCLIEditReopenMove(t *testing.T) {
+	dir := t.TempDir()
+
+	runCLI(t, dir, "-add", "task 1")
+	runCLI(t, dir, "-add", "tsak 2")
+	runCLI(t, dir, "-complete", "1")
+
+	runCLI(t, dir, "-edit", "2", "task 2")
+	runCLI(t, dir, "-reopen", "1")
+	runCLI(t, dir, "-move", "2", "1")
+
+	expected := "  2: task 2        \n" +
+		"  1: task 1        \n"
+	assertString(expected, runCLI(t, dir, "-list"), t)
+}
+
+// Execute tests for subtasks and blocked tasks
+func TestTodoCLISubtasks(t *testing.T) {
+	dir := t.TempDir()
+
+	runCLI(t, dir, "-add", "release")
+	runCLI(t, dir, "-add", "-parent", "1", "write notes")
+	runCLI(t, dir, "-add", "-parent", "1", "-blocked-by", "2", "tag version")
+
+	t.Run("ListTree", func(t *testing.T) {
+		expected := "  1: release        \n" +
+			"    2: write notes        \n" +
+			"    3: tag version [blocked by 2]        \n"
+		assertString(expected, runCLI(t, dir, "-list"), t)
+	})
+
+	t.Run("CompleteParent", func(t *testing.T) {
+		if err := cliCommand(t, dir, "-complete", "1").Run(); err == nil {
+			t.Fatal("Expected error completing parent with open subtasks, got no error.")
+		}
+
+		runCLI(t, dir, "-complete", "1", "-force")
+	})
+}
+
+// Execute tests for recurring tasks
+func TestTodoCLIRepeat(t *testing.T) {
+	dir := t.TempDir()
+
+	due := time.Now().AddDate(0, 0, 1)
+	runCLI(t, dir, "-add", "-repeat", "weekly", "-due", due.Format("2006-01-02"), "review")
+	runCLI(t, dir, "-complete", "1")
+
+	expected := fmt.Sprintf("  2: review due %s (repeats weekly)        \n",
+		due.AddDate(0, 0, 7).Format("2006-01-02"))
+	assertString(expected, runCLI(t, dir, "-list", "-c"), t)
+
+	if err := cliCommand(t, dir, "-add", "-repeat", "yearly", "task").Run(); err == nil {
+		t.Error("Expected error for invalid recurrence, got no error.")
+	}
+}
+
+// Execute tests for export and import of the list
+func TestTodoCLIExportImport(t *testing.T) {
+	dir := t.TempDir()
+
+	mdFile := filepath.Join(dir, "in.md")
+	if err := os.WriteFile(mdFile, []byte("- [x] task 1\n  - [ ] task 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runCLI(t, dir, "-import", mdFile)
+
+	expected := "- [x] task 1\n  - [ ] task 2\n"
+	assertString(expected, runCLI(t, dir, "-export", "md"), t)
+
+	if err := cliCommand(t, dir, "-export", "xml").Run(); err == nil {
+		t.Error("Expected error for unknown format, got no error.")
+	}
+}
+
+// Execute tests for searching and sorting of the listed tasks
+func TestTodoCLISearchSort(t *testing.T) {
+	dir := t.TempDir()
+
+	runCLI(t, dir, "-add", "buy milk")
+	runCLI(t, dir, "-add", "call Bob")
+	runCLI(t, dir, "-add", "Buy bread")
+	runCLI(t, dir, "-complete", "1")
+
+	today := time.Now().Format("2006-01-02")
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "Search",
+			args:     []string{"-list", "-search", "buy", "-sort", "alpha"},
+			expected: "  3: Buy bread        \nX 1: buy milk        \n",
+		},
+		{
+			name:     "Regex",
+			args:     []string{"-list", "-search", "^[a-z]+ [A-Z]", "-regex"},
+			expected: "  2: call Bob        \n",
+		},
+		{
+			name:     "Done",
+			args:     []string{"-list", "-done", "-since", today, "-until", today},
+			expected: "X 1: buy milk        \n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assertString(tc.expected, runCLI(t, dir, tc.args...), t)
+		})
+	}
+
+	t.Run("Template", func(t *testing.T) {
+		out := runCLI(t, dir, "-list", "-c", "-format", "template={{.ID}}:{{.Task}}")
+		assertString("2:call Bob\n3:Buy bread\n", out, t)
+	})
+
+	cmd := cliCommand(t, dir, "-list", "-sort", "size")
+	if err := cmd.Run(); err == nil {
+		t.Error("Expected error for invalid sort order, got no error.")
+	}
+}
+
+// Execute tests for undo and redo of the changes
+func TestTodoCLIUndoRedo(t *testing.T) {
+	dir := t.TempDir()
+
+	runCLI(t, dir, "-add", "task 1")
+	runCLI(t, dir, "-add", "task 2")
+	runCLI(t, dir, "-add", "task 3")
+	runCLI(t, dir, "-del", "2")
+
+	t.Run("Undo", func(t *testing.T) {
+		runCLI(t, dir, "-undo")
+		expected := "  1: task 1        \n" +
+			"  2: task 2        \n" +
+			"  3: task 3        \n"
+		assertString(expected, runCLI(t, dir, "-list"), t)
+	})
+
+	t.Run("Redo", func(t *testing.T) {
+		runCLI(t, dir, "-redo")
+		expected := "  1: task 1        \n" +
+			"  3: task 3        \n"
+		assertString(expected, runCLI(t, dir, "-list"), t)
+	})
+
+	t.Run("NothingToRedo", func(t *testing.T) {
+		cmd := cliCommand(t, dir, "-redo")
+		if err := cmd.Run(); err == nil {
+			t.Fatal("Expected error when nothing to redo, got no error.")
+		}
+	})
+}
+
+// Use several named lists in one file
+func TestTodoCLIProjects(t *testing.T) {
+	dir := t.TempDir()
+
+	runCLI(t, dir, "-add", "home task")
+	runCLI(t, dir, "-project", "work", "-add", "work task 1")
+	runCLI(t, dir, "-project", "work", "-add", "work task 2")
+	runCLI(t, dir, "-project", "work", "-complete", "1")
+
+	t.Run("ListProject", func(t *testing.T) {
+		expected := "X 1: work task 1        \n" +
+			"  2: work task 2        \n"
+		assertString(expected, runCLI(t, dir, "-project", "work", "-list"), t)
+		assertString("  1: home task        \n", runCLI(t, dir, "-list"), t)
+	})
+
+	t.Run("Projects", func(t *testing.T) {
+		expected := "default: 1 open of 1\n" +
+			"work: 1 open of 2\n"
+		assertString(expected, runCLI(t, dir, "-projects"), t)
+	})
+
+	t.Run("MoveToProject", func(t *testing.T) {
+		assertString("Moved to default as 2\n",
+			runCLI(t, dir, "-project", "work", "-move", "2", "-to-project", "default"), t)
+
+		expected := "  1: home task        \n" +
+			"  2: work task 2        \n"
+		assertString(expected, runCLI(t, dir, "-list"), t)
+		assertString("X 1: work task 1        \n", runCLI(t, dir, "-project", "work", "-list"), t)
+	})
+
+	t.Run("InvalidProject", func(t *testing.T) {
+		cmd := cliCommand(t, dir, "-project", "../x", "-list")
+		if err := cmd.Run(); err == nil {
+			t.Fatal("Expected error for invalid project name, got no error.")
+		}
+	})
+}
+
+// Archive, list, restore and purge completed tasks
+func TestTodoCLIArchive(t *testing.T) {
+	dir := t.TempDir()
+
+	runCLI(t, dir, "-add", "task 1")
+	runCLI(t, dir, "-add", "task 2")
+	runCLI(t, dir, "-add", "task 3")
+	runCLI(t, dir, "-complete", "1")
+	runCLI(t, dir, "-complete", "3")
+
+	t.Run("KeepRecent", func(t *testing.T) {
+		assertString("Archived 0 tasks\n", runCLI(t, dir, "-archive", "30d"), t)
+	})
+
+	t.Run("Archive", func(t *testing.T) {
+		assertString("Archived 2 tasks\n", runCLI(t, dir, "-archive", "1ns"), t)
+		assertString("  2: task 2        \n", runCLI(t, dir, "-list"), t)
+	})
+
+	t.Run("ListArchived", func(t *testing.T) {
+		expected := "X 1: task 1        \n" +
+			"X 2: task 3        \n"
+		assertString(expected, runCLI(t, dir, "-list", "-archived"), t)
+		assertString("X 2: task 3        \n", runCLI(t, dir, "-list", "-archived", "-search", "3"), t)
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		// IDs of archived items are not given again
+		assertString("Restored as 4\n", runCLI(t, dir, "-restore", "2"), t)
+		expected := "  2: task 2        \n" +
+			"X 4: task 3        \n"
+		assertString(expected, runCLI(t, dir, "-list"), t)
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		assertString("Purged 1 tasks\n", runCLI(t, dir, "-purge"), t)
+		assertString("", runCLI(t, dir, "-list", "-archived"), t)
+	})
+
+	t.Run("InvalidAge", func(t *testing.T) {
+		for _, age := range []string{"month", "0d", "-3d", "0s", "-1h"} {
+			cmd := cliCommand(t, dir, "-archive", age)
+			if err := cmd.Run(); err == nil {
+				t.Errorf("Expected error for invalid age %q, got no error.", age)
+			}
+		}
+	})
+
+	t.Run("ChangeArchived", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"-archived", "-complete", "2"},
+			{"-archived", "-del", "2"},
+			{"-archived", "-list", "-edit", "2", "renamed"},
+			{"-archived", "-add", "task 4"},
+		} {
+			cmd := cliCommand(t, dir, args...)
+			out, err := cmd.CombinedOutput()
+			if err == nil {
+				t.Errorf("Expected error for %v, got no error.", args)
+			}
+			if !strings.Contains(string(out), "cannot be used with -archived") {
+				t.Errorf("Expected the flags to be rejected for %v, got %q.", args, out)
+			}
+		}
+		expected := "  2: task 2        \n" +
+			"X 4: task 3        \n"
+		assertString(expected, runCLI(t, dir, "-list"), t)
+	})
+}
+
+// Complete and delete several tasks at once
+func TestTodoCLIBatch(t *testing.T) {
+	dir := t.TempDir()
+
+	for i := 1; i <= 6; i++ {
+		runCLI(t, dir, "-add", fmt.Sprintf("task %d", i))
+	}
+
+	t.Run("CompleteRanges", func(t *testing.T) {
+		assertString("Completed 4 tasks\n", runCLI(t, dir, "-complete", "1,3-5,4"), t)
+		expected := "X 1: task 1        \n" +
+			"  2: task 2        \n" +
+			"X 3: task 3        \n" +
+			"X 4: task 4        \n" +
+			"X 5: task 5        \n" +
+			"  6: task 6        \n"
+		assertString(expected, runCLI(t, dir, "-list"), t)
+	})
+
+	t.Run("AllOrNothing", func(t *testing.T) {
+		if err := cliCommand(t, dir, "-del", "2,9").Run(); err == nil {
+			t.Fatal("Expected error for unknown ID, got no error.")
+		}
+		if out := runCLI(t, dir, "-list", "-c"); out != "  2: task 2        \n  6: task 6        \n" {
+			t.Errorf("Expected list to be unchanged, got %q.", out)
+		}
+	})
+
+	t.Run("InvalidRange", func(t *testing.T) {
+		if err := cliCommand(t, dir, "-complete", "5-2").Run(); err == nil {
+			t.Fatal("Expected error for invalid range, got no error.")
+		}
+	})
+
+	t.Run("DeleteCompleted", func(t *testing.T) {
+		assertString("Deleted 4 tasks\n", runCLI(t, dir, "-del-completed"), t)
+		expected := "  2: task 2        \n" +
+			"  6: task 6        \n"
+		assertString(expected, runCLI(t, dir, "-list"), t)
+	})
+
+	t.Run("DeleteList", func(t *testing.T) {
+		assertString("Deleted 2 tasks\n", runCLI(t, dir, "-del", "2,6"), t)
+		assertString("", runCLI(t, dir, "-list"), t)
+	})
+
+	t.Run("MissingIDs", func(t *testing.T) {
+		runCLI(t, dir, "-add", "task 7")
+		out, err := cliCommand(t, dir, "-del", "2,7,9").CombinedOutput()
+		if err == nil {
+			t.Fatal("Expected error for unknown IDs, got no error.")
+		}
+		assertString("item not found: IDs 2, 9\n", string(out), t)
+	})
+
+	t.Run("CountChanged", func(t *testing.T) {
+		runCLI(t, dir, "-add", "task 8")
+		runCLI(t, dir, "-add", "task 9")
+
+		// Ranges skip the IDs of deleted items and end at the last item
+		assertString("Completed 2 tasks\n", runCLI(t, dir, "-complete", "1-8"), t)
+		assertString("Completed 1 tasks\n", runCLI(t, dir, "-complete", "7,8-1000000000"), t)
+		assertString("Deleted 3 tasks\n", runCLI(t, dir, "-del", "1-1000000000"), t)
+	})
+
+	t.Run("SingleRange", func(t *testing.T) {
+		runCLI(t, dir, "-add", "task 10")
+		assertString("Completed 1 tasks\n", runCLI(t, dir, "-complete", "10-10"), t)
+		assertString("Deleted 1 tasks\n", runCLI(t, dir, "-del", "10-10"), t)
+	})
+}
+
+// Remind about tasks due soon and overdue only once per urgency
+func TestTodoCLIRemind(t *testing.T) {
+	dir := t.TempDir()
+
+	today := time.Now()
+	yesterday := today.AddDate(0, 0, -1).Format("2006-01-02")
+	tomorrow := today.AddDate(0, 0, 1).Format("2006-01-02")
+	nextWeek := today.AddDate(0, 0, 7).Format("2006-01-02")
+
+	runCLI(t, dir, "-add", "-due", yesterday, "pay rent")
+	runCLI(t, dir, "-add", "-due", today.Format("2006-01-02"), "call mom")
+	runCLI(t, dir, "-add", "-due", tomorrow, "buy milk")
+	runCLI(t, dir, "-add", "-due", nextWeek, "plan trip")
+	runCLI(t, dir, "-add", "no due date")
+
+	t.Run("Remind", func(t *testing.T) {
+		expected := fmt.Sprintf("1: pay rent is overdue (due %s)\n", yesterday) +
+			fmt.Sprintf("2: call mom is due today (due %s)\n", today.Format("2006-01-02")) +
+			fmt.Sprintf("3: buy milk is due soon (due %s)\n", tomorrow)
+		assertString(expected, runCLI(t, dir, "-remind"), t)
+	})
+
+	t.Run("NotRepeated", func(t *testing.T) {
+		assertString("", runCLI(t, dir, "-remind"), t)
+	})
+
+	t.Run("LongerWindow", func(t *testing.T) {
+		expected := fmt.Sprintf("4: plan trip is due soon (due %s)\n", nextWeek)
+		assertString(expected, runCLI(t, dir, "-remind", "-remind-soon", "240h"), t)
+	})
+}
+
+func TestTodoCLINotesAttachments(t *testing.T) {
+	dir := t.TempDir()
+
+	attachment := filepath.Join(dir, "quote.txt")
+	if err := os.WriteFile(attachment, []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runCLI(t, dir, "-add", "call plumber")
+
+	show := "template={{.Notes}}|{{range .Attachments}}{{.Path}} {{.Size}} {{.SHA256}}{{end}}"
+
+	t.Run("Note", func(t *testing.T) {
+		runCLI(t, dir, "-note", "1", "ask about the boiler")
+		assertString("ask about the boiler|\n", runCLI(t, dir, "-list", "-format", show), t)
+	})
+
+	t.Run("MultiLineNote", func(t *testing.T) {
+		cmd := cliCommand(t, dir, "-note", "1")
+		cmd.Stdin = strings.NewReader("line 1\nline 2\n\nline 3\n")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		assertString("line 1\nline 2\n\nline 3|\n", runCLI(t, dir, "-list", "-format", show), t)
+	})
+
+	t.Run("Attach", func(t *testing.T) {
+		runCLI(t, dir, "-attach", "1", attachment)
+
+		expected := "line 1\nline 2\n\nline 3|" + attachment +
+			" 6 5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03\n"
+		assertString(expected, runCLI(t, dir, "-list", "-format", show), t)
+	})
+
+	t.Run("AttachMissingFile", func(t *testing.T) {
+		if err := cliCommand(t, dir, "-attach", "1", filepath.Join(dir, "missing.txt")).Run(); err == nil {
+			t.Fatal("Expected error for a missing file, got no error.")
+		}
+	})
+}
+
+func TestTodoCLIEncrypt(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "todo.json")
+
+	// Runs the tool with the key in the environment
+	run := func(t *testing.T, dir, key string, args ...string) (string, error) {
+		t.Helper()
+		cmd := cliCommand(t, dir, args...)
+		cmd.Env = append(cmd.Env, globals.KeyVariable+"="+key)
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+
+	if out, err := run(t, dir, "correct horse", "-add", "secret task"); err != nil {
+		t.Fatalf("%s: %s", err, out)
+	}
+
+	t.Run("EncryptedFile", func(t *testing.T) {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), "secret task") {
+			t.Errorf("Expected the task to be encrypted, got %q.", data)
+		}
+	})
+
+	t.Run("ListWithKey", func(t *testing.T) {
+		out, err := run(t, dir, "correct horse", "-list")
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		assertString("  1: secret task        \n", out, t)
+	})
+
+	t.Run("WrongKey", func(t *testing.T) {
+		if out, err := run(t, dir, "wrong horse", "-list"); err == nil {
+			t.Errorf("Expected error for a wrong key, got %q.", out)
+		}
+	})
+
+	t.Run("MissingKey", func(t *testing.T) {
+		out, err := run(t, dir, "", "-list")
+		if err == nil {
+			t.Fatalf("Expected error without a key, got %q.", out)
+		}
+		if !strings.Contains(out, globals.KeyVariable) {
+			t.Errorf("Expected the error to mention %s, got %q.", globals.KeyVariable, out)
+		}
+	})
+
+	t.Run("EncryptPlainFile", func(t *testing.T) {
+		plainDir := t.TempDir()
+
+		if out, err := run(t, plainDir, "", "-add", "plain task"); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		// A key does not open a plain file until it is encrypted
+		out, err := run(t, plainDir, "correct horse", "-list")
+		if err == nil || !strings.Contains(out, "-encrypt") {
+			t.Fatalf("Expected error pointing to -encrypt, got %q.", out)
+		}
+
+		out, err = run(t, plainDir, "correct horse", "-encrypt")
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		assertString("Encrypted 2 files\n", out, t)
+
+		out, err = run(t, plainDir, "correct horse", "-list")
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		assertString("  1: plain task        \n", out, t)
+
+		if out, err := run(t, plainDir, "", "-encrypt"); err == nil {
+			t.Errorf("Expected error without a key, got %q.", out)
+		}
+	})
+}
+
+func TestTodoCLISync(t *testing.T) {
+	dir := t.TempDir()
+
+	// Serves the list like todoServer does, requiring the token once it is set
+	var mu sync.Mutex
+	served := []byte("[]")
+	token := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprintf(w, `{"results": %s}`, served)
+		case http.MethodPut:
+			served, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	runCLI(t, dir, "-add", "task 1")
+	runCLI(t, dir, "-add", "task 2")
+
+	t.Run("Push", func(t *testing.T) {
+		assertString("Pulled 0, pushed 2, 0 conflicts\n", runCLI(t, dir, "-sync", "-remote", ts.URL), t)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if !strings.Contains(string(served), "task 2") {
+			t.Errorf("Expected the items on the server, got %s.", served)
+		}
+	})
+
+	t.Run("InSync", func(t *testing.T) {
+		assertString("Pulled 0, pushed 0, 0 conflicts\n", runCLI(t, dir, "-sync", "-remote", ts.URL), t)
+	})
+
+	t.Run("Token", func(t *testing.T) {
+		mu.Lock()
+		token = "secret"
+		mu.Unlock()
+
+		cmd := cliCommand(t, dir, "-sync", "-remote", ts.URL)
+		if out, err := cmd.CombinedOutput(); err == nil {
+			t.Fatalf("Expected an error without the token, got %q.", out)
+		}
+
+		cmd = cliCommand(t, dir, "-sync", "-remote", ts.URL)
+		cmd.Env = append(cmd.Env, globals.TokenVariable+"=secret")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		assertString("Pulled 0, pushed 0, 0 conflicts\n", string(out), t)
+	})
+
+	t.Run("MissingRemote", func(t *testing.T) {
+		cmd := cliCommand(t, dir, "-sync")
+		out, err := cmd.CombinedOutput()
+		if err == nil {
+			t.Fatalf("Expected an error, got %q.", out)
+		}
+		assertString("Missing -remote URL\n", string(out), t)
+	})
+}
+
+// Execute several processes that add tasks at the same time
+func TestTodoCLIConcurrentAdd(t *testing.T) {
+	dir := t.TempDir()
+
+	const count = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, count)
+	for i := 0; i < count; i++ {
+		cmd := cliCommand(t, dir, "-add", fmt.Sprintf("task %d", i))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if out, err := cmd.CombinedOutput(); err != nil {
+				errs <- fmt.Errorf("%s: %s", err, out)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	out := runCLI(t, dir, "-list")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != count {
+		t.Errorf("Expected %d tasks, got %d:\n%s", count, len(lines), out)
+	}
+}
+
+// Execute tests with the SQLite storage
+func TestTodoCLISQLite(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	env := fmt.Sprintf("%s=sqlite://%s",
+		globals.EnvironmentVariable, filepath.Join(t.TempDir(), "todo.db"))
+
+	for _, args := range [][]string{{"-add", "task 1"}, {"-add", "task 2"}, {"-complete", "1"}} {
+		cmd := exec.Command(cmdPath, args...)
+		cmd.Env = append(os.Environ(), env)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+	}
+
+	cmd := exec.Command(cmdPath, "-list")
+	cmd.Env = append(os.Environ(), env)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "X 1: task 1        \n" +
+		"  2: task 2        \n"
+	assertString(expected, string(out), t)
+}
+
 func listCommand(cmdPath string, t *testing.T) []byte {
 	cmd := exec.Command(cmdPath, "-list")
 	out, err := cmd.CombinedOutput()
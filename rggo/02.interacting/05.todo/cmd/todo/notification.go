@@ -0,0 +1,21 @@
+//go:build !containers && !disable_notification
+// +build !containers,!disable_notification
+
+package main
+
+import (
+	"rggo/distributing/notify"
+	"rggo/interacting/todo"
+)
+
+// Severity of the desktop notification grows with the urgency of the item
+var severities = map[todo.Urgency]notify.Severity{
+	todo.UrgencySoon:    notify.SeverityLow,
+	todo.UrgencyToday:   notify.SeverityNormal,
+	todo.UrgencyOverdue: notify.SeverityUrgent,
+}
+
+func sendNotification(r todo.Reminder) error {
+	n := notify.New("Todo", r.String(), severities[r.Urgency])
+	return n.Send()
+}
@@ -0,0 +1,10 @@
+//go:build containers || disable_notification
+// +build containers disable_notification
+
+package main
+
+import "rggo/interacting/todo"
+
+func sendNotification(r todo.Reminder) error {
+	return nil
+}
@@ -0,0 +1,121 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Layout used to parse and print due dates
+const DateFormat = "2006-01-02"
+
+// Returned when a priority is not one of high, medium or low
+var ErrInvalidPriority = errors.New("invalid priority")
+
+// Represents the priority of a todo item. Empty means no priority.
+type Priority string
+
+const (
+	PriorityHigh   Priority = "high"
+	PriorityMedium Priority = "medium"
+	PriorityLow    Priority = "low"
+)
+
+// Converts a string to a Priority. An empty string gives no priority.
+func ParsePriority(s string) (Priority, error) {
+	switch p := Priority(strings.ToLower(s)); p {
+	case "", PriorityHigh, PriorityMedium, PriorityLow:
+		return p, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidPriority, s)
+	}
+}
+
+// Sets an optional detail of a new todo item
+type Option func(*item)
+
+// Sets the priority of the item
+func WithPriority(p Priority) Option {
+	return func(t *item) {
+		t.Priority = p
+	}
+}
+
+// Sets the due date of the item. A new due date is reminded again.
+func WithDue(due time.Time) Option {
+	return func(t *item) {
+		if !t.Due.Equal(due) {
+			t.Reminded = UrgencyNone
+		}
+		t.Due = due
+	}
+}
+
+// Sets the tags of the item, replacing the ones it had. Empty tags are skipped.
+func WithTags(tags ...string) Option {
+	return func(t *item) {
+		t.Tags = nil
+		for _, tag := range tags {
+			tag = strings.TrimSpace(tag)
+			if tag != "" {
+				t.Tags = append(t.Tags, tag)
+			}
+		}
+	}
+}
+
+// Sets the notes of the item
+func WithNotes(notes string) Option {
+	return func(t *item) {
+		t.Notes = notes
+	}
+}
+
+// Changes the details of an existing item with the options
+func (l *List) SetDetails(id int, opts ...Option) error {
+	idx, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+
+	for _, opt := range opts {
+		opt(&l.Items[idx])
+	}
+	l.Items[idx].touch()
+
+	return nil
+}
+
+// Reports whether the item is marked with the tag
+func (t *item) hasTag(tag string) bool {
+	for _, tg := range t.Tags {
+		if strings.EqualFold(tg, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Formats priority, due date, tags and recurrence to append after the task name
+func detailsAsString(t *item) string {
+	details := ""
+	if t.Priority != "" {
+		details += fmt.Sprintf(" (%s)", t.Priority)
+	}
+
+	if !t.Due.IsZero() {
+		details += fmt.Sprintf(" due %s", t.Due.Format(DateFormat))
+	}
+
+	for _, tag := range t.Tags {
+		details += " #" + tag
+	}
+
+	if t.Repeat != nil {
+		details += fmt.Sprintf(" (repeats %s)", t.Repeat)
+	}
+
+	return details
+}
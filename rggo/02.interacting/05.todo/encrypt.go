@@ -0,0 +1,239 @@
+package todo
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+)
+
+var (
+	// Returned when an encrypted file is read without a key
+	ErrEncrypted = errors.New("file is encrypted")
+	// Returned when the key does not open an encrypted file or the file was changed
+	ErrDecrypt = errors.New("cannot decrypt: wrong key or corrupted file")
+	// Returned when the passphrase or the key file is empty
+	ErrEmptyKey = errors.New("empty encryption key")
+	// Returned when a plain file is read with a key. EncryptFile encrypts it.
+	ErrNotEncrypted = errors.New("file is not encrypted")
+)
+
+// Encrypted files start with the header followed by the salt of the key and the nonce.
+// The header also tells the format version.
+const encryptedHeader = "TODOENC1"
+
+const (
+	saltSize = 16
+	keySize  = 32
+
+	// Argon2id parameters: one pass over 64 MiB with 4 threads
+	argonTime    = 1
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+)
+
+// Encrypts files with AES-GCM using a key derived from a passphrase with Argon2id.
+// Every file keeps the salt of its key, so it can be decrypted with the passphrase only.
+// A nil Cipher reads and writes plain files.
+type Cipher struct {
+	passphrase []byte
+
+	// Deriving keys is slow on purpose, so they are kept by salt
+	mu   sync.Mutex
+	keys map[string][]byte
+	// Salt of the key of new files. Files read before give theirs.
+	salt []byte
+}
+
+// Returns a Cipher for the passphrase
+func NewCipher(passphrase []byte) (*Cipher, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrEmptyKey
+	}
+
+	return &Cipher{passphrase: passphrase, keys: map[string][]byte{}}, nil
+}
+
+// Returns a Cipher for the passphrase in the file. Trailing new lines are ignored.
+func NewCipherFromFile(filename string) (*Cipher, error) {
+	key, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCipher(bytes.TrimRight(key, "\r\n"))
+}
+
+// Reports whether the data starts with the header of encrypted files
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encryptedHeader))
+}
+
+// Returns the key for the salt, deriving it the first time
+func (c *Cipher) key(salt []byte) []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key, ok := c.keys[string(salt)]; ok {
+		return key
+	}
+
+	key := argon2.IDKey(c.passphrase, salt, argonTime, argonMemory, argonThreads, keySize)
+	c.keys[string(salt)] = key
+	if c.salt == nil {
+		c.salt = salt
+	}
+
+	return key
+}
+
+// Salt for new files: the salt of the first file read, or a random one
+func (c *Cipher) newSalt() ([]byte, error) {
+	c.mu.Lock()
+	salt := c.salt
+	c.mu.Unlock()
+
+	if salt != nil {
+		return salt, nil
+	}
+
+	salt = make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	return salt, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// Encrypts the data. The header and the salt are authenticated with it.
+func (c *Cipher) Encrypt(plain []byte) ([]byte, error) {
+	salt, err := c.newSalt()
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(c.key(salt))
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	header := append([]byte(encryptedHeader), salt...)
+	out := append(header[:len(header):len(header)], nonce...)
+	return gcm.Seal(out, nonce, plain, header), nil
+}
+
+// Decrypts data written by Encrypt
+func (c *Cipher) Decrypt(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, fmt.Errorf("%w: missing header", ErrDecrypt)
+	}
+
+	headerSize := len(encryptedHeader) + saltSize
+	if len(data) < headerSize {
+		return nil, fmt.Errorf("%w: truncated header", ErrDecrypt)
+	}
+	header := data[:headerSize]
+	salt := header[len(encryptedHeader):]
+
+	gcm, err := newGCM(c.key(append([]byte(nil), salt...)))
+	if err != nil {
+		return nil, err
+	}
+
+	rest := data[headerSize:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: truncated nonce", ErrDecrypt)
+	}
+	nonce, sealed := rest[:gcm.NonceSize()], rest[gcm.NonceSize():]
+
+	plain, err := gcm.Open(nil, nonce, sealed, header)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return plain, nil
+}
+
+// Reads the file and decrypts it with the cipher. A nil cipher reads plain files,
+// and a cipher reads encrypted files only, so a plain file put in place of an
+// encrypted one is not taken for it. A missing or empty file gives no data.
+func (c *Cipher) ReadFile(filename string) ([]byte, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if !IsEncrypted(data) {
+		if c != nil && len(data) > 0 {
+			return nil, fmt.Errorf("%w: %s", ErrNotEncrypted, filename)
+		}
+		return data, nil
+	}
+
+	if c == nil {
+		return nil, fmt.Errorf("%w: %s", ErrEncrypted, filename)
+	}
+
+	plain, err := c.Decrypt(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return plain, nil
+}
+
+// Encrypts the plain file in place. Returns false for missing, empty
+// and already encrypted files, which are left as they are.
+func (c *Cipher) EncryptFile(filename string) (bool, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if len(data) == 0 || IsEncrypted(data) {
+		return false, nil
+	}
+
+	return true, c.WriteFile(filename, data)
+}
+
+// Writes the data to the file atomically. Encrypted files are readable by the owner only.
+func (c *Cipher) WriteFile(filename string, data []byte) error {
+	if c == nil {
+		return writeFileAtomic(filename, data, 0644)
+	}
+
+	sealed, err := c.Encrypt(data)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filename, sealed, 0600)
+}
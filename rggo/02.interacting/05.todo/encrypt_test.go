@@ -0,0 +1,123 @@
+package todo_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"rggo/interacting/todo"
+)
+
+func TestCipher(t *testing.T) {
+	c, err := todo.NewCipher([]byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain := []byte(`[{"Task":"Task 1"}]`)
+
+	sealed, err := c.Encrypt(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !todo.IsEncrypted(sealed) {
+		t.Errorf("Expected the encrypted data to have the header, got %q.", sealed[:8])
+	}
+	if todo.IsEncrypted(plain) {
+		t.Errorf("Expected plain data without the header.")
+	}
+	if bytes.Contains(sealed, []byte("Task 1")) {
+		t.Errorf("Expected the task to be encrypted, got %q.", sealed)
+	}
+
+	t.Run("SameKey", func(t *testing.T) {
+		// A new cipher derives the key from the salt in the data
+		other, _ := todo.NewCipher([]byte("correct horse"))
+		got, err := other.Decrypt(sealed)
+		if err != nil {
+			t.Fatalf("Expected no error, got %q.", err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Errorf("Expected %q, got %q.", plain, got)
+		}
+	})
+
+	t.Run("WrongKey", func(t *testing.T) {
+		other, _ := todo.NewCipher([]byte("wrong horse"))
+		if _, err := other.Decrypt(sealed); !errors.Is(err, todo.ErrDecrypt) {
+			t.Errorf("Expected %q, got %q.", todo.ErrDecrypt, err)
+		}
+	})
+
+	t.Run("Corrupted", func(t *testing.T) {
+		changed := bytes.Clone(sealed)
+		changed[len(changed)-1] ^= 1
+		if _, err := c.Decrypt(changed); !errors.Is(err, todo.ErrDecrypt) {
+			t.Errorf("Expected %q, got %q.", todo.ErrDecrypt, err)
+		}
+	})
+
+	t.Run("EmptyKey", func(t *testing.T) {
+		if _, err := todo.NewCipher(nil); !errors.Is(err, todo.ErrEmptyKey) {
+			t.Errorf("Expected %q, got %q.", todo.ErrEmptyKey, err)
+		}
+	})
+}
+
+// Tests that lists are encrypted on save and that a key is needed to read them
+func TestSaveWithCipher(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "todo.json")
+
+	keyFile := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyFile, []byte("correct horse\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := todo.NewCipherFromFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Plain files are not read with a key until they are encrypted
+	l := todo.List{}
+	l.Add("Task 1")
+	if err := l.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	p := todo.Projects{}
+	if err := p.GetWith(filename, c); !errors.Is(err, todo.ErrNotEncrypted) {
+		t.Fatalf("Expected %q, got %q.", todo.ErrNotEncrypted, err)
+	}
+	if encrypted, err := c.EncryptFile(filename); err != nil || !encrypted {
+		t.Fatalf("Expected the file to be encrypted, got %t, %v.", encrypted, err)
+	}
+	if encrypted, err := c.EncryptFile(filename); err != nil || encrypted {
+		t.Errorf("Expected the encrypted file to be left as it is, got %t, %v.", encrypted, err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !todo.IsEncrypted(data) {
+		t.Fatalf("Expected encrypted file, got %q.", data)
+	}
+
+	if err := l.Get(filename); !errors.Is(err, todo.ErrEncrypted) {
+		t.Errorf("Expected %q, got %q.", todo.ErrEncrypted, err)
+	}
+
+	// The key file holds the passphrase
+	passphrase, _ := todo.NewCipher([]byte("correct horse"))
+	loaded := todo.Projects{}
+	if err := loaded.GetWith(filename, passphrase); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+	if got := loaded[todo.DefaultProject]; len(got.Items) != 1 || got.Items[0].Task != "Task 1" {
+		t.Errorf("Expected Task 1, got %v.", got)
+	}
+}
@@ -0,0 +1,143 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Returned for a sort order other than created, completed or alpha
+var ErrInvalidSort = errors.New("invalid sort order")
+
+// Completion states to filter by
+const (
+	StatusAny  = ""
+	StatusOpen = "open"
+	StatusDone = "done"
+)
+
+// Sort orders of the filtered list. Empty keeps the list order.
+const (
+	SortCreated   = "created"
+	SortCompleted = "completed"
+	SortAlpha     = "alpha"
+)
+
+// Describes which items to keep when filtering a list and how to sort them.
+// Zero fields do not restrict the result.
+// Date ranges include the Since time and exclude the Until time.
+type Filter struct {
+	Tag       string
+	Priority  Priority
+	DueBefore time.Time
+
+	// Case insensitive substring of the task name
+	Search string
+	// Regular expression matching the task name
+	Regexp *regexp.Regexp
+	Status string
+
+	CreatedSince   time.Time
+	CreatedUntil   time.Time
+	CompletedSince time.Time
+	CompletedUntil time.Time
+
+	Sort string
+}
+
+// Checks that the sort order is known
+func ParseSort(s string) (string, error) {
+	switch s {
+	case "", SortCreated, SortCompleted, SortAlpha:
+		return s, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidSort, s)
+	}
+}
+
+// Returns a new list with the items that match the filter in the filter sort order
+func (l *List) Select(f Filter) List {
+	selected := List{NextID: l.NextID}
+	for _, t := range l.Items {
+		if f.match(&t) {
+			selected.Items = append(selected.Items, t)
+		}
+	}
+
+	f.sort(selected)
+	return selected
+}
+
+func (f *Filter) match(t *item) bool {
+	if f.Tag != "" && !t.hasTag(f.Tag) {
+		return false
+	}
+
+	if f.Priority != "" && t.Priority != f.Priority {
+		return false
+	}
+
+	if !f.DueBefore.IsZero() && (t.Due.IsZero() || !t.Due.Before(f.DueBefore)) {
+		return false
+	}
+
+	if f.Search != "" && !strings.Contains(strings.ToLower(t.Task), strings.ToLower(f.Search)) {
+		return false
+	}
+
+	if f.Regexp != nil && !f.Regexp.MatchString(t.Task) {
+		return false
+	}
+
+	if (f.Status == StatusOpen && t.Done) || (f.Status == StatusDone && !t.Done) {
+		return false
+	}
+
+	if !inRange(t.CreatedAt, f.CreatedSince, f.CreatedUntil) {
+		return false
+	}
+
+	if (!f.CompletedSince.IsZero() || !f.CompletedUntil.IsZero()) &&
+		(!t.Done || !inRange(t.CompletedAt, f.CompletedSince, f.CompletedUntil)) {
+		return false
+	}
+
+	return true
+}
+
+func (f *Filter) sort(l List) {
+	var less func(a, b *item) bool
+	switch f.Sort {
+	case SortCreated:
+		less = func(a, b *item) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	case SortCompleted:
+		// Open items go after the completed ones
+		less = func(a, b *item) bool {
+			if a.Done != b.Done {
+				return a.Done
+			}
+			return a.CompletedAt.Before(b.CompletedAt)
+		}
+	case SortAlpha:
+		less = func(a, b *item) bool { return strings.ToLower(a.Task) < strings.ToLower(b.Task) }
+	default:
+		return
+	}
+
+	sort.SliceStable(l.Items, func(i, j int) bool { return less(&l.Items[i], &l.Items[j]) })
+}
+
+func inRange(t, since, until time.Time) bool {
+	if !since.IsZero() && t.Before(since) {
+		return false
+	}
+
+	if !until.IsZero() && !t.Before(until) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,78 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Returned when an item reference is not like 3 or work/3
+var ErrInvalidItemRef = errors.New("invalid item reference")
+
+// Reference to the item that other tools, like pomo, keep to refer to it:
+// the ID for the default project, project/ID for the others
+func ItemRef(project string, id int) string {
+	if project == DefaultProject {
+		return strconv.Itoa(id)
+	}
+
+	return project + "/" + strconv.Itoa(id)
+}
+
+// Splits the reference into the project and the ID of the item
+func ParseItemRef(ref string) (string, int, error) {
+	project, idPart := DefaultProject, ref
+	if k := strings.LastIndex(ref, "/"); k >= 0 {
+		project, idPart = ref[:k], ref[k+1:]
+		if err := ValidateProject(project); err != nil {
+			return "", 0, fmt.Errorf("%w: %q", ErrInvalidItemRef, ref)
+		}
+	}
+
+	id, err := strconv.Atoi(idPart)
+	if err != nil || id < 1 {
+		return "", 0, fmt.Errorf("%w: %q", ErrInvalidItemRef, ref)
+	}
+
+	return project, id, nil
+}
+
+// Source of the time spent focused on items, like the pomo repositories
+type FocusSource interface {
+	// Focus time by item reference
+	FocusByTask() (map[string]time.Duration, error)
+}
+
+// Reads the focus time of the items of the project from the source.
+// It is shown in the verbose output and is not saved with the list.
+// References to other projects or to missing items are ignored.
+func (l *List) LoadFocus(project string, src FocusSource) error {
+	focus, err := src.FocusByTask()
+	if err != nil {
+		return err
+	}
+
+	for ref, d := range focus {
+		p, id, err := ParseItemRef(ref)
+		if err != nil || p != project {
+			continue
+		}
+
+		if idx, err := l.Index(id); err == nil {
+			l.Items[idx].focused += d
+		}
+	}
+
+	return nil
+}
+
+// Focus time rounded to the minute like 1h15m, or to the second under a minute
+func formatFocus(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+
+	return strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+}
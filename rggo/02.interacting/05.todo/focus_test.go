@@ -0,0 +1,81 @@
+package todo_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"rggo/interacting/todo"
+)
+
+func TestItemRef(t *testing.T) {
+	testCases := []struct {
+		ref     string
+		project string
+		id      int
+		err     error
+	}{
+		{ref: "3", project: todo.DefaultProject, id: 3},
+		{ref: "work/12", project: "work", id: 12},
+		{ref: "work/", err: todo.ErrInvalidItemRef},
+		{ref: "/3", err: todo.ErrInvalidItemRef},
+		{ref: "0", err: todo.ErrInvalidItemRef},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.ref, func(t *testing.T) {
+			project, id, err := todo.ParseItemRef(tc.ref)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("Expected %q, got %q.", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %q.", err)
+			}
+
+			if project != tc.project || id != tc.id {
+				t.Errorf("Expected %s and %d, got %s and %d.", tc.project, tc.id, project, id)
+			}
+			if ref := todo.ItemRef(project, id); ref != tc.ref {
+				t.Errorf("Expected %q, got %q.", tc.ref, ref)
+			}
+		})
+	}
+}
+
+// Focus time by reference, like a pomo repository reports it
+type focusSource map[string]time.Duration
+
+func (f focusSource) FocusByTask() (map[string]time.Duration, error) {
+	return f, nil
+}
+
+func TestLoadFocus(t *testing.T) {
+	l := todo.List{}
+	l.Add("Task 1")
+	l.Add("Task 2")
+
+	src := focusSource{
+		"1":      75 * time.Minute,
+		"work/2": 25 * time.Minute,
+		"9":      25 * time.Minute,
+	}
+	if err := l.LoadFocus(todo.DefaultProject, src); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	rows := l.Rows(true, false)
+	if !strings.HasSuffix(rows[0].Text, "    Focused: 1h15m") {
+		t.Errorf("Expected the focus time of task 1, got %q.", rows[0].Text)
+	}
+	if strings.Contains(rows[1].Text, "Focused") {
+		t.Errorf("Expected no focus time for task 2 of the project, got %q.", rows[1].Text)
+	}
+
+	if strings.Contains(l.Print(false, false), "Focused") {
+		t.Errorf("Expected the focus time in the verbose output only.")
+	}
+}
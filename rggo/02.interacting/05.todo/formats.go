@@ -0,0 +1,475 @@
+package todo
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Import and export formats
+const (
+	FormatMarkdown = "md"
+	FormatCSV      = "csv"
+	FormatTodoTxt  = "todo.txt"
+)
+
+// Returned for a format other than md, csv or todo.txt
+var ErrUnknownFormat = errors.New("unknown format")
+
+// Columns of the CSV format. The attachments are a JSON array.
+var csvHeader = []string{
+	"id", "task", "done", "created_at", "completed_at",
+	"priority", "due", "tags", "parent", "blocked_by", "repeat",
+	"notes", "attachments",
+}
+
+// Returns the format for a file name by its extension
+func FormatFromFilename(filename string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".md", ".markdown":
+		return FormatMarkdown, nil
+	case ".csv":
+		return FormatCSV, nil
+	case ".txt":
+		return FormatTodoTxt, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownFormat, filename)
+	}
+}
+
+// Writes the list to w in the given format
+func (l *List) Export(w io.Writer, format string) error {
+	switch format {
+	case FormatMarkdown:
+		return l.exportMarkdown(w)
+	case FormatCSV:
+		return l.exportCSV(w)
+	case FormatTodoTxt, "txt":
+		return l.exportTodoTxt(w)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+// Reads items in the given format from r and appends them to the list.
+// Imported items get new IDs. References between them are kept.
+func (l *List) Import(r io.Reader, format string) error {
+	var imported []item
+	var err error
+
+	switch format {
+	case FormatMarkdown:
+		imported, err = importMarkdown(r)
+	case FormatCSV:
+		imported, err = importCSV(r)
+	case FormatTodoTxt, "txt":
+		imported, err = importTodoTxt(r)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Subtasks must not be their own ancestors, or they would not be printed
+	checked := List{}
+	checked.appendRenumbered(imported)
+	if err := checked.checkParents(); err != nil {
+		return err
+	}
+
+	l.appendRenumbered(checked.Items)
+	return nil
+}
+
+// Appends items with new IDs and updates the references between them.
+// References to items that were not imported are dropped.
+func (l *List) appendRenumbered(imported []item) {
+	ids := make(map[int]int, len(imported))
+	next := l.nextID()
+	for k := range imported {
+		ids[imported[k].ID] = next
+		imported[k].ID = next
+		next++
+	}
+
+	for k := range imported {
+		t := &imported[k]
+		t.Parent = ids[t.Parent]
+
+		blockedBy := []int{}
+		for _, b := range t.BlockedBy {
+			if id, ok := ids[b]; ok {
+				blockedBy = append(blockedBy, id)
+			}
+		}
+		t.BlockedBy = nil
+		if len(blockedBy) > 0 {
+			t.BlockedBy = blockedBy
+		}
+	}
+
+	l.Items = append(l.Items, imported...)
+}
+
+// GitHub-style checklist. Subtasks are indented by two spaces.
+func (l *List) exportMarkdown(w io.Writer) error {
+	for _, n := range l.tree() {
+		mark := " "
+		if n.t.Done {
+			mark = "x"
+		}
+
+		indent := strings.Repeat("  ", n.depth)
+		if _, err := fmt.Fprintf(w, "%s- [%s] %s\n", indent, mark, n.t.Task); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var markdownItem = regexp.MustCompile(`^(\s*)[-*+] \[([ xX])\] (.*)$`)
+
+func importMarkdown(r io.Reader) ([]item, error) {
+	l := []item{}
+	// IDs of the last item on each level of indentation
+	parents := []int{}
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		m := markdownItem.FindStringSubmatch(s.Text())
+		if m == nil {
+			continue
+		}
+
+		depth := len(strings.ReplaceAll(m[1], "\t", "  ")) / 2
+		if depth > len(parents) {
+			depth = len(parents)
+		}
+		parents = parents[:depth]
+
+		t := item{
+			ID:        len(l) + 1,
+			Task:      strings.TrimSpace(m[3]),
+			Done:      m[2] != " ",
+			CreatedAt: time.Now(),
+		}
+		t.UpdatedAt = t.CreatedAt
+		if t.Done {
+			t.CompletedAt = t.CreatedAt
+		}
+		if depth > 0 {
+			t.Parent = parents[depth-1]
+		}
+
+		l = append(l, t)
+		parents = append(parents, t.ID)
+	}
+
+	return l, s.Err()
+}
+
+func (l *List) exportCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, t := range l.Items {
+		repeat := ""
+		if t.Repeat != nil {
+			repeat = t.Repeat.String()
+		}
+
+		blockedBy := make([]string, len(t.BlockedBy))
+		for k, b := range t.BlockedBy {
+			blockedBy[k] = strconv.Itoa(b)
+		}
+
+		attachments := ""
+		if len(t.Attachments) > 0 {
+			js, err := json.Marshal(t.Attachments)
+			if err != nil {
+				return err
+			}
+			attachments = string(js)
+		}
+
+		record := []string{
+			strconv.Itoa(t.ID), t.Task, strconv.FormatBool(t.Done),
+			formatTime(t.CreatedAt), formatTime(t.CompletedAt),
+			string(t.Priority), formatTime(t.Due), strings.Join(t.Tags, ","),
+			strconv.Itoa(t.Parent), strings.Join(blockedBy, ","), repeat,
+			t.Notes, attachments,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func importCSV(r io.Reader) ([]item, error) {
+	cr := csv.NewReader(r)
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	l := []item{}
+	if len(records) == 0 {
+		return l, nil
+	}
+
+	// Columns are matched by the header so they can be in any order
+	col := map[string]int{}
+	for k, name := range records[0] {
+		col[name] = k
+	}
+	if _, ok := col["task"]; !ok {
+		return nil, fmt.Errorf("%w: CSV header has no %q column", ErrUnknownFormat, "task")
+	}
+
+	for line, record := range records[1:] {
+		get := func(name string) string {
+			if k, ok := col[name]; ok && k < len(record) {
+				return record[k]
+			}
+			return ""
+		}
+
+		t, err := parseCSVItem(get)
+		if err != nil {
+			return nil, fmt.Errorf("CSV line %d: %w", line+2, err)
+		}
+		if t.ID == 0 {
+			t.ID = -(line + 1)
+		}
+		l = append(l, t)
+	}
+
+	return l, nil
+}
+
+func parseCSVItem(get func(string) string) (item, error) {
+	var err error
+	t := item{Task: get("task")}
+
+	if v := get("id"); v != "" {
+		if t.ID, err = strconv.Atoi(v); err != nil {
+			return t, err
+		}
+	}
+	if v := get("done"); v != "" {
+		if t.Done, err = strconv.ParseBool(v); err != nil {
+			return t, err
+		}
+	}
+	if t.CreatedAt, err = parseTime(get("created_at")); err != nil {
+		return t, err
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	if t.CompletedAt, err = parseTime(get("completed_at")); err != nil {
+		return t, err
+	}
+	if t.Priority, err = ParsePriority(get("priority")); err != nil {
+		return t, err
+	}
+	if t.Due, err = parseTime(get("due")); err != nil {
+		return t, err
+	}
+	WithTags(strings.Split(get("tags"), ",")...)(&t)
+	if v := get("parent"); v != "" {
+		if t.Parent, err = strconv.Atoi(v); err != nil {
+			return t, err
+		}
+	}
+	for _, v := range strings.Split(get("blocked_by"), ",") {
+		if v == "" {
+			continue
+		}
+		b, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return t, err
+		}
+		t.BlockedBy = append(t.BlockedBy, b)
+	}
+	if v := get("repeat"); v != "" {
+		if t.Repeat, err = ParseRecurrence(v); err != nil {
+			return t, err
+		}
+	}
+	t.Notes = get("notes")
+	if v := get("attachments"); v != "" {
+		if err := json.Unmarshal([]byte(v), &t.Attachments); err != nil {
+			return t, fmt.Errorf("invalid attachments: %w", err)
+		}
+	}
+
+	return t, nil
+}
+
+// Priorities of the todo.txt format
+var todoTxtPriorities = map[Priority]string{
+	PriorityHigh:   "A",
+	PriorityMedium: "B",
+	PriorityLow:    "C",
+}
+
+// One item per line: x COMPLETED CREATED (PRIORITY) task +tag due:DATE rec:RULE
+func (l *List) exportTodoTxt(w io.Writer) error {
+	for _, t := range l.Items {
+		fields := []string{}
+		if t.Done {
+			fields = append(fields, "x", t.CompletedAt.Format(DateFormat))
+		} else if p, ok := todoTxtPriorities[t.Priority]; ok {
+			fields = append(fields, "("+p+")")
+		}
+		fields = append(fields, t.CreatedAt.Format(DateFormat), t.Task)
+
+		for _, tag := range t.Tags {
+			fields = append(fields, "+"+tag)
+		}
+		if p, ok := todoTxtPriorities[t.Priority]; ok && t.Done {
+			fields = append(fields, "pri:"+p)
+		}
+		if !t.Due.IsZero() {
+			fields = append(fields, "due:"+t.Due.Format(DateFormat))
+		}
+		if t.Repeat != nil {
+			fields = append(fields, "rec:"+t.Repeat.String())
+		}
+
+		if _, err := fmt.Fprintln(w, strings.Join(fields, " ")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var (
+	todoTxtDate     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	todoTxtPriority = regexp.MustCompile(`^\(([A-Z])\)$`)
+)
+
+func importTodoTxt(r io.Reader) ([]item, error) {
+	l := []item{}
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		t, err := parseTodoTxtItem(fields)
+		if err != nil {
+			return nil, fmt.Errorf("todo.txt line %q: %w", s.Text(), err)
+		}
+		t.ID = len(l) + 1
+		l = append(l, t)
+	}
+
+	return l, s.Err()
+}
+
+func parseTodoTxtItem(fields []string) (item, error) {
+	t := item{}
+
+	if fields[0] == "x" {
+		t.Done = true
+		fields = fields[1:]
+		if len(fields) > 0 && todoTxtDate.MatchString(fields[0]) {
+			t.CompletedAt, _ = time.ParseInLocation(DateFormat, fields[0], time.Local)
+			fields = fields[1:]
+		}
+	}
+
+	if len(fields) > 0 {
+		if m := todoTxtPriority.FindStringSubmatch(fields[0]); m != nil {
+			t.Priority = priorityFromTodoTxt(m[1])
+			fields = fields[1:]
+		}
+	}
+
+	if len(fields) > 0 && todoTxtDate.MatchString(fields[0]) {
+		t.CreatedAt, _ = time.ParseInLocation(DateFormat, fields[0], time.Local)
+		fields = fields[1:]
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	if t.Done && t.CompletedAt.IsZero() {
+		t.CompletedAt = t.CreatedAt
+	}
+
+	words := []string{}
+	for _, f := range fields {
+		key, value, found := strings.Cut(f, ":")
+		switch {
+		case len(f) > 1 && (f[0] == '+' || f[0] == '@'):
+			t.Tags = append(t.Tags, f[1:])
+		case found && key == "due":
+			due, err := time.ParseInLocation(DateFormat, value, time.Local)
+			if err != nil {
+				return t, err
+			}
+			t.Due = due
+		case found && key == "rec":
+			r, err := ParseRecurrence(value)
+			if err != nil {
+				return t, err
+			}
+			t.Repeat = r
+		case found && key == "pri":
+			t.Priority = priorityFromTodoTxt(value)
+		default:
+			words = append(words, f)
+		}
+	}
+	t.Task = strings.Join(words, " ")
+
+	return t, nil
+}
+
+// Maps A to high, B to medium and anything lower to low priority
+func priorityFromTodoTxt(p string) Priority {
+	switch p {
+	case "A":
+		return PriorityHigh
+	case "B":
+		return PriorityMedium
+	default:
+		return PriorityLow
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(time.RFC3339Nano)
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339Nano, s)
+}
@@ -0,0 +1,182 @@
+package todo_test
+
+import (
+	"bytes"
+	"errors"
+	"rggo/interacting/todo"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tests that exporting and importing keeps the items
+func TestExportImport(t *testing.T) {
+	// Arrange
+	due := time.Date(2026, time.November, 1, 0, 0, 0, 0, time.Local)
+	r, _ := todo.ParseRecurrence("weekly:mon")
+	l := todo.List{}
+	l.Add("Release", todo.WithPriority(todo.PriorityHigh), todo.WithTags("ops"), todo.WithDue(due))
+	l.Add("Write notes", todo.WithRepeat(r))
+	l.Add("Tag version")
+	l.SetParent(2, 1)
+	l.Block(3, 2)
+	l.Complete(3)
+
+	testCases := []struct {
+		format       string
+		keepsDetails bool
+		keepsTime    bool
+	}{
+		{format: todo.FormatMarkdown},
+		{format: todo.FormatCSV, keepsDetails: true, keepsTime: true},
+		{format: todo.FormatTodoTxt, keepsDetails: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			// Act
+			var buf bytes.Buffer
+			if err := l.Export(&buf, tc.format); err != nil {
+				t.Fatalf("Expected no error, got %q.", err)
+			}
+
+			imported := todo.List{}
+			imported.Add("Existing")
+			if err := imported.Import(&buf, tc.format); err != nil {
+				t.Fatalf("Expected no error, got %q.", err)
+			}
+
+			// Assert
+			if len(imported.Items) != len(l.Items)+1 {
+				t.Fatalf("Expected %d items, got %d.", len(l.Items)+1, len(imported.Items))
+			}
+			for k, exp := range l.Items {
+				got := imported.Items[k+1]
+				if got.ID != exp.ID+1 || got.Task != exp.Task || got.Done != exp.Done {
+					t.Errorf("Expected %d %q done %t, got %d %q done %t.",
+						exp.ID+1, exp.Task, exp.Done, got.ID, got.Task, got.Done)
+				}
+				if tc.keepsTime && !got.CreatedAt.Equal(exp.CreatedAt) {
+					t.Errorf("Expected created at %s, got %s.", exp.CreatedAt, got.CreatedAt)
+				}
+				if tc.keepsTime && !got.CompletedAt.Equal(exp.CompletedAt) {
+					t.Errorf("Expected completed at %s, got %s.", exp.CompletedAt, got.CompletedAt)
+				}
+			}
+
+			if imported.Items[2].Parent != 2 && tc.format != todo.FormatTodoTxt {
+				t.Errorf("Expected parent %d, got %d.", 2, imported.Items[2].Parent)
+			}
+
+			if tc.keepsDetails {
+				got := imported.Items[1]
+				if got.Priority != todo.PriorityHigh || !got.Due.Equal(due) ||
+					len(got.Tags) != 1 || got.Tags[0] != "ops" {
+					t.Errorf("Expected details to be kept, got %v.", got)
+				}
+				if imported.Items[2].Repeat == nil || imported.Items[2].Repeat.String() != "weekly:mon" {
+					t.Errorf("Expected recurrence to be kept, got %v.", imported.Items[2].Repeat)
+				}
+			}
+		})
+	}
+}
+
+// Tests that the CSV format keeps the notes and the attachments
+func TestExportImportCSVNotes(t *testing.T) {
+	l := todo.List{}
+	l.Add("Release", todo.WithNotes("Check the changelog,\nthen \"tag\" it"))
+	a := todo.Attachment{Path: "/tmp/notes.txt", Size: 6, SHA256: strings.Repeat("ab", 32)}
+	l.Attach(1, a)
+	l.Add("Tag version")
+
+	var buf bytes.Buffer
+	if err := l.Export(&buf, todo.FormatCSV); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	imported := todo.List{}
+	if err := imported.Import(&buf, todo.FormatCSV); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	got := imported.Items[0]
+	if got.Notes != l.Items[0].Notes {
+		t.Errorf("Expected notes %q, got %q.", l.Items[0].Notes, got.Notes)
+	}
+	if len(got.Attachments) != 1 || got.Attachments[0] != a {
+		t.Errorf("Expected attachment %v, got %v.", a, got.Attachments)
+	}
+	if imported.Items[1].Notes != "" || len(imported.Items[1].Attachments) != 0 {
+		t.Errorf("Expected no notes and attachments, got %v.", imported.Items[1])
+	}
+}
+
+// Tests the exported Markdown checklist
+func TestExportMarkdown(t *testing.T) {
+	l := todo.List{}
+	l.Add("Release")
+	l.Add("Write notes")
+	l.SetParent(2, 1)
+	l.Complete(2)
+
+	var buf bytes.Buffer
+	l.Export(&buf, todo.FormatMarkdown)
+
+	expected := "- [ ] Release\n" +
+		"  - [x] Write notes\n"
+	assertString(expected, buf.String(), t)
+}
+
+// Tests parsing of todo.txt lines written by other tools
+func TestImportTodoTxt(t *testing.T) {
+	input := "(A) 2026-10-01 Call Mom +family @phone due:2026-10-20\n" +
+		"x 2026-10-03 2026-10-02 Pay bills\n" +
+		"\n" +
+		"Plain task\n"
+
+	l := todo.List{}
+	if err := l.Import(strings.NewReader(input), todo.FormatTodoTxt); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	if len(l.Items) != 3 {
+		t.Fatalf("Expected 3 items, got %d.", len(l.Items))
+	}
+	if l.Items[0].Task != "Call Mom" || l.Items[0].Priority != todo.PriorityHigh || len(l.Items[0].Tags) != 2 {
+		t.Errorf("Unexpected first item %v.", l.Items[0])
+	}
+	if l.Items[0].CreatedAt.Format(todo.DateFormat) != "2026-10-01" || l.Items[0].Due.Format(todo.DateFormat) != "2026-10-20" {
+		t.Errorf("Unexpected dates of the first item %v.", l.Items[0])
+	}
+	if !l.Items[1].Done || l.Items[1].CompletedAt.Format(todo.DateFormat) != "2026-10-03" {
+		t.Errorf("Expected second item completed on 2026-10-03, got %v.", l.Items[1])
+	}
+	if l.Items[2].Task != "Plain task" || l.Items[2].Done {
+		t.Errorf("Unexpected third item %v.", l.Items[2])
+	}
+}
+
+// Tests that imported subtasks cannot be their own ancestors
+func TestImportParentCycle(t *testing.T) {
+	l := todo.List{}
+	l.Add("Existing task")
+
+	csv := "id,task,parent\n1,Task A,2\n2,Task B,1\n"
+	if err := l.Import(strings.NewReader(csv), todo.FormatCSV); !errors.Is(err, todo.ErrInvalidParent) {
+		t.Errorf("Expected error %q, got %q.", todo.ErrInvalidParent, err)
+	}
+	if len(l.Items) != 1 {
+		t.Errorf("Expected the list to be unchanged, got %v.", l.Items)
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	l := todo.List{}
+	if err := l.Export(&bytes.Buffer{}, "xml"); !errors.Is(err, todo.ErrUnknownFormat) {
+		t.Errorf("Expected error %q, got %q.", todo.ErrUnknownFormat, err)
+	}
+	if _, err := todo.FormatFromFilename("list.xml"); !errors.Is(err, todo.ErrUnknownFormat) {
+		t.Errorf("Expected error %q, got %q.", todo.ErrUnknownFormat, err)
+	}
+}
@@ -1,3 +1,28 @@
 module rggo/interacting/todo
 
-go 1.18
+// The notify and pomo modules need go 1.21.4, so it is the lowest version
+// of the module. The other dependencies need go 1.19 at most.
+go 1.21.4
+
+require (
+	github.com/mattn/go-sqlite3 v1.14.22
+	github.com/mum4k/termdash v0.13.0
+	golang.org/x/crypto v0.17.0
+	golang.org/x/term v0.15.0
+	rggo/distributing/notify v0.0.0
+	rggo/interactiveTools/pomo v0.0.0
+)
+
+require (
+	github.com/gdamore/encoding v1.0.0 // indirect
+	github.com/gdamore/tcell/v2 v2.0.0 // indirect
+	github.com/kylelemons/godebug v1.1.0 // indirect
+	github.com/lucasb-eyer/go-colorful v1.0.3 // indirect
+	github.com/mattn/go-runewidth v0.0.9 // indirect
+	golang.org/x/sys v0.15.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
+
+replace rggo/distributing/notify => ../../11.distributing/notify
+
+replace rggo/interactiveTools/pomo => ../../11.distributing/pomo
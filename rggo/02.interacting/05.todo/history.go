@@ -0,0 +1,291 @@
+package todo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Maximum number of operations kept in the history
+const MaxHistory = 50
+
+// Kinds of recorded operations
+const (
+	OpAdd      = "add"
+	OpComplete = "complete"
+	OpDelete   = "delete"
+	OpEdit     = "edit"
+	OpMove     = "move"
+)
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+	// Returned by Undo and Redo when an item changed since the operation
+	ErrHistoryConflict = errors.New("item changed since the operation")
+)
+
+// Change of a single item. Before is nil for added items, After is nil for deleted ones.
+type Change struct {
+	Op        string
+	ID        int
+	Before    *item
+	After     *item
+	BeforePos int
+	AfterPos  int
+}
+
+// All changes made to the list by one command
+type Entry struct {
+	At      time.Time
+	Changes []Change
+}
+
+// Journal of operations on a list.
+// Entries before Current can be undone, the rest can be redone.
+type History struct {
+	Entries []Entry
+	Current int
+}
+
+// Records the difference between the list before and after an operation.
+// Entries that could be redone are dropped and only the last MaxHistory entries are kept.
+func (h *History) Record(before, after List) {
+	changes := diff(before, after)
+	if len(changes) == 0 {
+		return
+	}
+
+	h.Entries = append(h.Entries[:h.Current], Entry{
+		At:      time.Now(),
+		Changes: changes,
+	})
+
+	if len(h.Entries) > MaxHistory {
+		h.Entries = h.Entries[len(h.Entries)-MaxHistory:]
+	}
+	h.Current = len(h.Entries)
+}
+
+// Reverts the last recorded operation
+func (l *List) Undo(h *History) error {
+	if h.Current == 0 {
+		return ErrNothingToUndo
+	}
+
+	if err := l.apply(h.Entries[h.Current-1].Changes, true); err != nil {
+		return err
+	}
+
+	h.Current--
+	return nil
+}
+
+// Applies again the last undone operation
+func (l *List) Redo(h *History) error {
+	if h.Current >= len(h.Entries) {
+		return ErrNothingToRedo
+	}
+
+	if err := l.apply(h.Entries[h.Current].Changes, false); err != nil {
+		return err
+	}
+
+	h.Current++
+	return nil
+}
+
+// Encodes the History as JSON and saves it using the provided file name
+func (h *History) Save(filename string) error {
+	return h.SaveWith(filename, nil)
+}
+
+// Saves the History like Save, encrypted with the cipher unless it is nil
+func (h *History) SaveWith(filename string, c *Cipher) error {
+	js, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteFile(filename, js)
+}
+
+// Opens the provided file name and decodes the JSON data into the History
+func (h *History) Get(filename string) error {
+	return h.GetWith(filename, nil)
+}
+
+// Gets the History like Get, decrypting the file with the cipher if it is encrypted
+func (h *History) GetWith(filename string, c *Cipher) error {
+	file, err := c.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	if len(file) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(file, h)
+}
+
+// Copy of the item without the sent reminder.
+// Sent reminders are not operations to undo.
+func (t item) withoutReminder() item {
+	t.Reminded = UrgencyNone
+	return t
+}
+
+// Puts the changed items into the state they had before (undo) or after the operation.
+// Nothing is changed if an item is no longer in the state the operation left it
+// (undo) or found it (redo), so changes made since are not overwritten.
+func (l *List) apply(changes []Change, undo bool) error {
+	changed := make(map[int]bool, len(changes))
+	for _, c := range changes {
+		changed[c.ID] = true
+
+		expected := c.Before
+		if undo {
+			expected = c.After
+		}
+		idx, err := l.Index(c.ID)
+		switch {
+		case expected == nil && err == nil:
+			return fmt.Errorf("%w: item %d was added again", ErrHistoryConflict, c.ID)
+		case expected != nil && err != nil:
+			return fmt.Errorf("%w: item %d was deleted", ErrHistoryConflict, c.ID)
+		case expected != nil && !l.Items[idx].sameContent(expected):
+			return fmt.Errorf("%w: item %d was changed", ErrHistoryConflict, c.ID)
+		}
+	}
+
+	kept := []item{}
+	for _, t := range l.Items {
+		if !changed[t.ID] {
+			kept = append(kept, t)
+		} else {
+			l.reserve(t.ID)
+		}
+	}
+
+	type placement struct {
+		pos int
+		t   item
+	}
+	targets := []placement{}
+	for _, c := range changes {
+		state, pos := c.After, c.AfterPos
+		if undo {
+			state, pos = c.Before, c.BeforePos
+		}
+		if state != nil {
+			// Restoring a state is a change of the item
+			t := state.clone()
+			t.touch()
+			targets = append(targets, placement{pos: pos, t: t})
+		}
+	}
+
+	// Inserting in ascending order puts every item at its recorded position
+	sort.Slice(targets, func(i, j int) bool { return targets[i].pos < targets[j].pos })
+	for _, p := range targets {
+		pos := p.pos
+		if pos > len(kept) {
+			pos = len(kept)
+		}
+		kept = append(kept[:pos], append([]item{p.t}, kept[pos:]...)...)
+	}
+
+	l.Items = kept
+	return nil
+}
+
+// Finds the changes that turn the list before into the list after
+func diff(before, after List) []Change {
+	afterPos := make(map[int]int, len(after.Items))
+	for pos, t := range after.Items {
+		afterPos[t.ID] = pos
+	}
+
+	stay := notMoved(before, afterPos)
+	changes := []Change{}
+	beforeIDs := make(map[int]bool, len(before.Items))
+	for pos, t := range before.Items {
+		beforeIDs[t.ID] = true
+		b := t.clone()
+
+		ap, ok := afterPos[t.ID]
+		if !ok {
+			changes = append(changes, Change{Op: OpDelete, ID: t.ID, Before: &b, BeforePos: pos})
+			continue
+		}
+
+		a := after.Items[ap].clone()
+		same := reflect.DeepEqual(b.withoutReminder(), a.withoutReminder())
+		if same && stay[t.ID] {
+			continue
+		}
+
+		op := OpEdit
+		switch {
+		case same:
+			op = OpMove
+		case !b.Done && a.Done:
+			op = OpComplete
+		}
+		changes = append(changes, Change{
+			Op: op, ID: t.ID, Before: &b, After: &a, BeforePos: pos, AfterPos: ap,
+		})
+	}
+
+	for pos, t := range after.Items {
+		if !beforeIDs[t.ID] {
+			a := t.clone()
+			changes = append(changes, Change{Op: OpAdd, ID: t.ID, After: &a, AfterPos: pos})
+		}
+	}
+
+	return changes
+}
+
+// Returns the IDs of the items that kept their order relative to each other:
+// the longest increasing subsequence of their positions in the list after.
+func notMoved(before List, afterPos map[int]int) map[int]bool {
+	ids := []int{}
+	positions := []int{}
+	for _, t := range before.Items {
+		if pos, ok := afterPos[t.ID]; ok {
+			ids = append(ids, t.ID)
+			positions = append(positions, pos)
+		}
+	}
+
+	// tails[k] is the index of the smallest tail of an increasing subsequence of length k+1
+	tails := []int{}
+	prev := make([]int, len(positions))
+	for i, pos := range positions {
+		k := sort.Search(len(tails), func(j int) bool { return positions[tails[j]] >= pos })
+		prev[i] = -1
+		if k > 0 {
+			prev[i] = tails[k-1]
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+
+	stay := make(map[int]bool, len(tails))
+	if len(tails) == 0 {
+		return stay
+	}
+	for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+		stay[ids[i]] = true
+	}
+
+	return stay
+}
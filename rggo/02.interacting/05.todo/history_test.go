@@ -0,0 +1,151 @@
+package todo_test
+
+import (
+	"errors"
+	"rggo/interacting/todo"
+	"testing"
+)
+
+// Tests undo and redo of recorded operations
+func TestUndoRedo(t *testing.T) {
+	// Arrange
+	l := todo.List{}
+	h := &todo.History{}
+
+	record := func(fn func()) {
+		before := l.Clone()
+		fn()
+		h.Record(before, l)
+	}
+
+	record(func() { l.Add("Task 1"); l.Add("Task 2"); l.Add("Task 3") })
+	record(func() { l.Complete(2) })
+	record(func() { l.Delete(1) })
+
+	// Act, Assert
+	if err := l.Undo(h); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+	assertIDs(t, l, 1, 2, 3)
+
+	if err := l.Undo(h); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+	if l.Items[1].Done {
+		t.Errorf("Expected item 2 not to be completed after undo.")
+	}
+
+	if err := l.Redo(h); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+	if !l.Items[1].Done {
+		t.Errorf("Expected item 2 to be completed after redo.")
+	}
+
+	if err := l.Undo(h); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+	if err := l.Undo(h); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+	assertIDs(t, l)
+
+	if err := l.Undo(h); !errors.Is(err, todo.ErrNothingToUndo) {
+		t.Errorf("Expected error %q, got %q.", todo.ErrNothingToUndo, err)
+	}
+
+	// A new operation drops the entries that could be redone
+	record(func() { l.Add("Task 4") })
+	if err := l.Redo(h); !errors.Is(err, todo.ErrNothingToRedo) {
+		t.Errorf("Expected error %q, got %q.", todo.ErrNothingToRedo, err)
+	}
+}
+
+// Tests that the order of the items is restored by undo
+func TestUndoMove(t *testing.T) {
+	// Arrange
+	l := todo.List{}
+	l.Add("Task 1")
+	l.Add("Task 2")
+	l.Add("Task 3")
+	h := &todo.History{}
+
+	before := l.Clone()
+	l.Move(3, 1)
+	h.Record(before, l)
+
+	if len(h.Entries[0].Changes) != 1 || h.Entries[0].Changes[0].Op != todo.OpMove {
+		t.Fatalf("Expected a single move, got %+v.", h.Entries[0].Changes)
+	}
+
+	// Act
+	l.Undo(h)
+
+	// Assert
+	assertIDs(t, l, 1, 2, 3)
+}
+
+// Tests that undo and redo do not overwrite changes made since the operation
+func TestUndoRedoConflict(t *testing.T) {
+	// Arrange
+	l := todo.List{}
+	h := &todo.History{}
+	l.Add("Task 1")
+	l.Add("Task 2")
+
+	before := l.Clone()
+	l.Complete(1)
+	h.Record(before, l)
+
+	// Changed without recording it, as another program would
+	l.Edit(1, "Changed")
+
+	// Act, Assert
+	if err := l.Undo(h); !errors.Is(err, todo.ErrHistoryConflict) {
+		t.Fatalf("Expected error %q, got %q.", todo.ErrHistoryConflict, err)
+	}
+	if !l.Items[0].Done || l.Items[0].Task != "Changed" {
+		t.Errorf("Expected the changed item to be kept, got %+v.", l.Items[0])
+	}
+	if h.Current != 1 {
+		t.Errorf("Expected the entry to stay undoable, got current %d.", h.Current)
+	}
+
+	l.Edit(1, "Task 1")
+	if err := l.Undo(h); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	l.Delete(1)
+	if err := l.Redo(h); !errors.Is(err, todo.ErrHistoryConflict) {
+		t.Errorf("Expected error %q, got %q.", todo.ErrHistoryConflict, err)
+	}
+}
+
+// Tests that the history keeps at most MaxHistory entries
+func TestHistoryBounded(t *testing.T) {
+	l := todo.List{}
+	h := &todo.History{}
+	for i := 0; i < todo.MaxHistory+10; i++ {
+		before := l.Clone()
+		l.Add("Task")
+		h.Record(before, l)
+	}
+
+	if len(h.Entries) != todo.MaxHistory || h.Current != todo.MaxHistory {
+		t.Errorf("Expected %d entries, got %d (current %d).", todo.MaxHistory, len(h.Entries), h.Current)
+	}
+}
+
+func assertIDs(t *testing.T, l todo.List, ids ...int) {
+	t.Helper()
+
+	if len(l.Items) != len(ids) {
+		t.Fatalf("Expected %d items, got %d.", len(ids), len(l.Items))
+	}
+	for k, id := range ids {
+		if l.Items[k].ID != id {
+			t.Errorf("Expected ID %d at position %d, got %d.", id, k, l.Items[k].ID)
+		}
+	}
+}
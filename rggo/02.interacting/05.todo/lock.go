@@ -0,0 +1,78 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// How long to wait for another process to release the lock
+const DefaultLockTimeout = 5 * time.Second
+
+// How often to retry while waiting for the lock
+const lockRetryInterval = 10 * time.Millisecond
+
+// Returned when the lock is still held by another process after the timeout
+var ErrLockTimeout = errors.New("timeout waiting for lock")
+
+// Describes a failure to acquire or release a file lock
+type LockError struct {
+	Path string
+	Err  error
+}
+
+func (e *LockError) Error() string {
+	return fmt.Sprintf("lock %s: %s", e.Path, e.Err)
+}
+
+func (e *LockError) Unwrap() error {
+	return e.Err
+}
+
+// Advisory lock on a file shared by several processes
+type FileLock struct {
+	file *os.File
+}
+
+// Acquires an exclusive advisory lock on the file, creating it if needed.
+// Waits up to timeout for other processes to release the lock.
+func LockFile(path string, timeout time.Duration) (*FileLock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, &LockError{Path: path, Err: err}
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, &LockError{Path: path, Err: err}
+		}
+		if locked {
+			return &FileLock{file: f}, nil
+		}
+
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, &LockError{Path: path, Err: ErrLockTimeout}
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// Releases the lock
+func (fl *FileLock) Unlock() error {
+	path := fl.file.Name()
+	if err := unlock(fl.file); err != nil {
+		fl.file.Close()
+		return &LockError{Path: path, Err: err}
+	}
+
+	if err := fl.file.Close(); err != nil {
+		return &LockError{Path: path, Err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package todo_test
+
+import (
+	"errors"
+	"path/filepath"
+	"rggo/interacting/todo"
+	"testing"
+	"time"
+)
+
+// Tests that a second lock waits for the first one and fails with a typed error
+func TestLockFile(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "todo.json.lock")
+	lock, err := todo.LockFile(path, time.Second)
+	if err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	// Act
+	_, err = todo.LockFile(path, 50*time.Millisecond)
+
+	// Assert
+	if !errors.Is(err, todo.ErrLockTimeout) {
+		t.Fatalf("Expected error %q, got %q.", todo.ErrLockTimeout, err)
+	}
+
+	var lockErr *todo.LockError
+	if !errors.As(err, &lockErr) || lockErr.Path != path {
+		t.Errorf("Expected *todo.LockError for %q, got %#v.", path, err)
+	}
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	lock, err = todo.LockFile(path, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected no error after unlock, got %q.", err)
+	}
+	lock.Unlock()
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+package todo
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Tries to lock the file without blocking. Reports false if it is locked by another process.
+func tryLock(f *os.File) (bool, error) {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func unlock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,77 @@
+package todo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Reference to a file attached to an item. The file is not copied:
+// the size and the checksum tell whether it changed since it was attached.
+type Attachment struct {
+	Path   string
+	Size   int64
+	SHA256 string
+}
+
+// Describes the file to attach. Relative paths are made absolute.
+func NewAttachment(path string) (Attachment, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	f, err := os.Open(abs)
+	if err != nil {
+		return Attachment{}, err
+	}
+	defer f.Close()
+
+	return ReadAttachment(abs, f)
+}
+
+// Describes the content read from r as the attachment at the path
+func ReadAttachment(path string, r io.Reader) (Attachment, error) {
+	h := sha256.New()
+	size, err := io.Copy(h, r)
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	return Attachment{Path: path, Size: size, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// Replaces the notes of the item. Notes can have several lines; empty notes remove them.
+func (l *List) SetNotes(id int, notes string) error {
+	idx, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+
+	l.Items[idx].Notes = notes
+	l.Items[idx].touch()
+	return nil
+}
+
+// Attaches the file to the item. A file attached before at the same path is replaced.
+func (l *List) Attach(id int, a Attachment) error {
+	idx, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+
+	t := &l.Items[idx]
+	for k := range t.Attachments {
+		if t.Attachments[k].Path == a.Path {
+			t.Attachments[k] = a
+			t.touch()
+			return nil
+		}
+	}
+
+	t.Attachments = append(t.Attachments, a)
+	t.touch()
+	return nil
+}
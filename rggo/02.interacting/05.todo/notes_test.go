@@ -0,0 +1,40 @@
+package todo_test
+
+import (
+	"strings"
+	"testing"
+
+	"rggo/interacting/todo"
+)
+
+func TestNotesAttachments(t *testing.T) {
+	l := todo.List{}
+	l.Add("Task 1")
+
+	if err := l.SetNotes(1, "first line\nsecond line"); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+	if l.Items[0].Notes != "first line\nsecond line" {
+		t.Errorf("Expected the notes to be set, got %q.", l.Items[0].Notes)
+	}
+
+	a, err := todo.ReadAttachment("/tmp/a.txt", strings.NewReader("hello\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Size != 6 || a.SHA256 != "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03" {
+		t.Errorf("Expected size 6 and the checksum of the content, got %+v.", a)
+	}
+
+	l.Attach(1, a)
+	changed, _ := todo.ReadAttachment("/tmp/a.txt", strings.NewReader("hello again\n"))
+	l.Attach(1, changed)
+
+	if len(l.Items[0].Attachments) != 1 || l.Items[0].Attachments[0].Size != 12 {
+		t.Errorf("Expected the attachment to be replaced, got %+v.", l.Items[0].Attachments)
+	}
+
+	if err := l.Attach(2, a); err == nil {
+		t.Errorf("Expected an error for a missing item.")
+	}
+}
@@ -0,0 +1,141 @@
+package todo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+)
+
+// Output formats of a list
+const (
+	OutputText     = "text"
+	OutputTable    = "table"
+	OutputJSON     = "json"
+	outputTemplate = "template="
+)
+
+// Stable machine-readable view of an item used by the JSON and template outputs
+type ItemView struct {
+	ID          int          `json:"id"`
+	Task        string       `json:"task"`
+	Done        bool         `json:"done"`
+	CreatedAt   time.Time    `json:"created_at"`
+	CompletedAt *time.Time   `json:"completed_at"`
+	Priority    string       `json:"priority"`
+	Due         *time.Time   `json:"due"`
+	Tags        []string     `json:"tags"`
+	Parent      int          `json:"parent"`
+	BlockedBy   []int        `json:"blocked_by"`
+	Repeat      string       `json:"repeat"`
+	Notes       string       `json:"notes"`
+	Attachments []Attachment `json:"attachments"`
+}
+
+// Returns the views of the items in the list order
+func (l *List) Views() []ItemView {
+	views := make([]ItemView, 0, len(l.Items))
+	for _, t := range l.Items {
+		v := ItemView{
+			ID:          t.ID,
+			Task:        t.Task,
+			Done:        t.Done,
+			CreatedAt:   t.CreatedAt,
+			Priority:    string(t.Priority),
+			Tags:        append([]string{}, t.Tags...),
+			Parent:      t.Parent,
+			BlockedBy:   append([]int{}, t.BlockedBy...),
+			Notes:       t.Notes,
+			Attachments: append([]Attachment{}, t.Attachments...),
+		}
+		if !t.CompletedAt.IsZero() {
+			completed := t.CompletedAt
+			v.CompletedAt = &completed
+		}
+		if !t.Due.IsZero() {
+			due := t.Due
+			v.Due = &due
+		}
+		if t.Repeat != nil {
+			v.Repeat = t.Repeat.String()
+		}
+
+		views = append(views, v)
+	}
+
+	return views
+}
+
+// Writes the list in the format:
+//
+//	text                - same as Print
+//	table               - aligned columns
+//	json                - array of ItemView
+//	template=<template> - text/template executed for each ItemView
+func (l *List) Write(w io.Writer, format string, verbose bool) error {
+	switch {
+	case format == "" || format == OutputText:
+		_, err := io.WriteString(w, l.Print(verbose, false))
+		return err
+	case format == OutputTable:
+		return l.writeTable(w)
+	case format == OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(l.Views())
+	case strings.HasPrefix(format, outputTemplate):
+		return l.writeTemplate(w, strings.TrimPrefix(format, outputTemplate))
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+func (l *List) writeTable(w io.Writer) error {
+	// minimum column width - 1 character
+	// tabwidth - 2 characters
+	// padding - 1 character
+	// pad character - whitespace (' ')
+	// disable additional flags - 0
+	tw := tabwriter.NewWriter(w, 1, 2, 1, ' ', 0)
+	fmt.Fprintf(tw, "\tID\tTask\tPriority\tDue\tTags\n")
+	for _, t := range l.Items {
+		done := "-"
+		if t.Done {
+			done = "X"
+		}
+
+		due := ""
+		if !t.Due.IsZero() {
+			due = t.Due.Format(DateFormat)
+		}
+
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n",
+			done, t.ID, t.Task, t.Priority, due, strings.Join(t.Tags, ","))
+	}
+
+	// Flush the output to the io.Writer
+	return tw.Flush()
+}
+
+func (l *List) writeTemplate(w io.Writer, text string) error {
+	// Templates usually come from the command line without a trailing new line
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+
+	tmpl, err := template.New("item").Parse(text)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range l.Views() {
+		if err := tmpl.Execute(w, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package todo_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"rggo/interacting/todo"
+	"testing"
+	"time"
+)
+
+// Tests the table, JSON and template outputs of the list
+func TestWrite(t *testing.T) {
+	// Arrange
+	due := time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)
+	l := todo.List{}
+	l.Add("Release", todo.WithPriority(todo.PriorityHigh), todo.WithDue(due), todo.WithTags("ops", "infra"))
+	l.Add("Write notes")
+	l.Complete(2)
+
+	t.Run("Table", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := l.Write(&buf, todo.OutputTable, false); err != nil {
+			t.Fatalf("Expected no error, got %q.", err)
+		}
+
+		expected := "  ID Task        Priority Due        Tags\n" +
+			"- 1  Release     high     2026-11-01 ops,infra\n" +
+			"X 2  Write notes                     \n"
+		assertString(expected, buf.String(), t)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := l.Write(&buf, todo.OutputJSON, false); err != nil {
+			t.Fatalf("Expected no error, got %q.", err)
+		}
+
+		var views []map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &views); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(views) != 2 || views[0]["task"] != "Release" || views[0]["priority"] != "high" {
+			t.Fatalf("Unexpected JSON output %s.", buf.String())
+		}
+		if views[0]["completed_at"] != nil || views[1]["completed_at"] == nil {
+			t.Errorf("Expected completed_at only for the completed item, got %s.", buf.String())
+		}
+		if views[0]["due"] != "2026-11-01T00:00:00Z" {
+			t.Errorf("Expected due date %q, got %v.", "2026-11-01T00:00:00Z", views[0]["due"])
+		}
+	})
+
+	t.Run("Template", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := l.Write(&buf, `template={{.ID}} {{.Task}}{{if .Done}} (done){{end}}`, false)
+		if err != nil {
+			t.Fatalf("Expected no error, got %q.", err)
+		}
+
+		expected := "1 Release\n2 Write notes (done)\n"
+		assertString(expected, buf.String(), t)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		err := l.Write(&bytes.Buffer{}, "yaml", false)
+		if !errors.Is(err, todo.ErrUnknownFormat) {
+			t.Errorf("Expected error %q, got %q.", todo.ErrUnknownFormat, err)
+		}
+	})
+}
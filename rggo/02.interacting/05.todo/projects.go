@@ -0,0 +1,173 @@
+package todo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// Name of the list used when no project is given
+const DefaultProject = "default"
+
+var ErrInvalidProject = errors.New("invalid project")
+
+// Project names are used in file names and URL paths
+var projectName = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+// Checks that the name can be used for a project
+func ValidateProject(name string) error {
+	if !projectName.MatchString(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidProject, name)
+	}
+
+	return nil
+}
+
+// Named lists kept in a single file
+type Projects map[string]List
+
+// Names of the projects in alphabetical order
+func (p Projects) Names() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Encodes the Projects as JSON and saves them using the provided file name.
+// A file with the default project only is saved as a plain list,
+// so it can be read by older versions, unless the list keeps its next ID.
+func (p Projects) Save(filename string) error {
+	return p.SaveWith(filename, nil)
+}
+
+// Saves the Projects like Save, encrypted with the cipher unless it is nil
+func (p Projects) SaveWith(filename string, c *Cipher) error {
+	var js []byte
+	var err error
+
+	if _, ok := p[DefaultProject]; len(p) == 0 || ok && len(p) == 1 {
+		js, err = json.Marshal(p[DefaultProject])
+	}
+	// A plain list is an array, so a list that keeps its next ID goes in the map
+	if err == nil && (js == nil || js[0] != '[') {
+		js, err = json.Marshal(map[string]List(p))
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.WriteFile(filename, js)
+}
+
+// Opens the provided file name and decodes the projects.
+// A file with a plain list gives the default project.
+func (p *Projects) Get(filename string) error {
+	return p.GetWith(filename, nil)
+}
+
+// Gets the Projects like Get, decrypting the file with the cipher if it is encrypted
+func (p *Projects) GetWith(filename string, c *Cipher) error {
+	file, err := c.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	file = bytes.TrimSpace(file)
+	if len(file) == 0 {
+		return nil
+	}
+
+	if *p == nil {
+		*p = Projects{}
+	}
+
+	if file[0] == '[' {
+		l := List{}
+		if err := json.Unmarshal(file, &l); err != nil {
+			return err
+		}
+		(*p)[DefaultProject] = l
+	} else if err := json.Unmarshal(file, (*map[string]List)(p)); err != nil {
+		return err
+	}
+
+	for name, l := range *p {
+		l.migrate()
+		(*p)[name] = l
+	}
+
+	return nil
+}
+
+// Moves the item to the end of another project of the storage and returns its new ID.
+// The item leaves its parent and blockers behind, as they belong to the old project.
+// The destination is saved first, so a failure never loses the item.
+func MoveToProject(s ProjectStorage, id int, from, to string) (int, error) {
+	if err := ValidateProject(to); err != nil {
+		return 0, err
+	}
+	if from == to {
+		return 0, fmt.Errorf("%w: item %d is already in %q", ErrInvalidProject, id, to)
+	}
+
+	src, err := s.Project(from)
+	if err != nil {
+		return 0, err
+	}
+
+	dst, err := s.Project(to)
+	if err != nil {
+		return 0, err
+	}
+
+	// Projects of a storage share its lock
+	if lk, ok := s.(Locker); ok {
+		if err := lk.Lock(); err != nil {
+			return 0, err
+		}
+		defer lk.Unlock()
+	}
+
+	newID := 0
+	err = modify(src, func(sl *List, sh *History) error {
+		idx, err := sl.Index(id)
+		if err != nil {
+			return err
+		}
+
+		before := sl.Clone()
+		t := sl.Items[idx].clone()
+		if err := sl.Delete(id); err != nil {
+			return err
+		}
+
+		err = modify(dst, func(dl *List, dh *History) error {
+			dstBefore := dl.Clone()
+
+			t.ID = dl.nextID()
+			t.Parent = 0
+			t.BlockedBy = nil
+			t.touch()
+			dl.Items = append(dl.Items, t)
+			newID = t.ID
+
+			dh.Record(dstBefore, *dl)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		sh.Record(before, *sl)
+		return nil
+	})
+
+	return newID, err
+}
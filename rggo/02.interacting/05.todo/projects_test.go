@@ -0,0 +1,91 @@
+package todo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"rggo/interacting/todo"
+)
+
+// Tests that a file with a single list is still read and written as a plain list
+func TestProjectsSaveGet(t *testing.T) {
+	// Arrange
+	filename := filepath.Join(t.TempDir(), "todo.json")
+
+	l := todo.List{}
+	l.Add("Home task")
+	if err := l.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	p := todo.Projects{}
+	if err := p.Get(filename); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	work := todo.List{}
+	work.Add("Work task")
+	p["work"] = work
+	if err := p.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	names := p.Names()
+	if len(names) != 2 || names[0] != todo.DefaultProject || names[1] != "work" {
+		t.Errorf("Expected projects [default work], got %v.", names)
+	}
+
+	l2 := todo.List{}
+	if err := l2.Get(filename); err != nil {
+		t.Fatal(err)
+	}
+	if len(l2.Items) != 1 || l2.Items[0].Task != "Home task" {
+		t.Errorf("Expected the default project, got %v.", l2)
+	}
+
+	// Saving the default list keeps the other projects
+	l2.Add("Another home task")
+	if err := l2.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	p2 := todo.Projects{}
+	if err := p2.Get(filename); err != nil {
+		t.Fatal(err)
+	}
+	if len(p2["work"].Items) != 1 || len(p2[todo.DefaultProject].Items) != 2 {
+		t.Errorf("Expected 2 default and 1 work items, got %v.", p2)
+	}
+}
+
+func TestProjectsSaveDefaultOnly(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+
+	p := todo.Projects{todo.DefaultProject: todo.List{}}
+	if err := p.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "[]" {
+		t.Errorf("Expected a plain list, got %q.", data)
+	}
+}
+
+func TestValidateProject(t *testing.T) {
+	for _, name := range []string{"", "a b", "a/b", "..", ".hidden"} {
+		if err := todo.ValidateProject(name); err == nil {
+			t.Errorf("Expected error for project %q.", name)
+		}
+	}
+
+	if err := todo.ValidateProject("work-2026"); err != nil {
+		t.Errorf("Expected no error, got %q.", err)
+	}
+}
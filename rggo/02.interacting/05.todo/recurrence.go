@@ -0,0 +1,205 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Returned when a recurrence rule cannot be parsed
+var ErrInvalidRecurrence = errors.New("invalid recurrence")
+
+// Recurrence frequencies
+const (
+	RepeatDaily   = "daily"
+	RepeatWeekly  = "weekly"
+	RepeatMonthly = "monthly"
+	RepeatEvery   = "every"
+)
+
+// Rule to create the next occurrence of a completed item
+type Recurrence struct {
+	Freq string
+	// Number of days between occurrences for the "every" frequency
+	Days int
+	// Days of the week for the "weekly" frequency. Empty means the weekday of the due date.
+	Weekdays []time.Weekday
+	// Day of the month for the "monthly" frequency. Zero means the day of the due date.
+	// Shorter months use their last day.
+	Day int
+}
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Parses a recurrence rule:
+//
+//	daily, weekly, weekly:mon,thu, monthly, monthly:31 (day), every:3 (days)
+func ParseRecurrence(s string) (*Recurrence, error) {
+	freq, arg, _ := strings.Cut(strings.ToLower(strings.TrimSpace(s)), ":")
+	r := &Recurrence{Freq: freq}
+
+	switch freq {
+	case RepeatDaily:
+		if arg != "" {
+			return nil, fmt.Errorf("%w: %q takes no arguments", ErrInvalidRecurrence, s)
+		}
+	case RepeatMonthly:
+		if arg == "" {
+			break
+		}
+		day, err := strconv.Atoi(arg)
+		if err != nil || day < 1 || day > 31 {
+			return nil, fmt.Errorf("%w: %q needs a day of the month from 1 to 31", ErrInvalidRecurrence, s)
+		}
+		r.Day = day
+	case RepeatWeekly:
+		if arg == "" {
+			break
+		}
+		for _, name := range strings.Split(arg, ",") {
+			wd, err := parseWeekday(name)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q: %s", ErrInvalidRecurrence, s, err)
+			}
+			r.Weekdays = append(r.Weekdays, wd)
+		}
+	case RepeatEvery:
+		days, err := strconv.Atoi(arg)
+		if err != nil || days < 1 {
+			return nil, fmt.Errorf("%w: %q needs a positive number of days", ErrInvalidRecurrence, s)
+		}
+		r.Days = days
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidRecurrence, s)
+	}
+
+	return r, nil
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	s = strings.TrimSpace(s)
+	for k, name := range weekdayNames {
+		if len(s) >= 3 && strings.HasPrefix(name, s[:3]) {
+			return time.Weekday(k), nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown weekday %q", s)
+}
+
+// Formats the rule in the syntax accepted by ParseRecurrence
+func (r *Recurrence) String() string {
+	switch r.Freq {
+	case RepeatWeekly:
+		if len(r.Weekdays) == 0 {
+			return r.Freq
+		}
+		names := make([]string, len(r.Weekdays))
+		for k, wd := range r.Weekdays {
+			names[k] = weekdayNames[wd]
+		}
+		return r.Freq + ":" + strings.Join(names, ",")
+	case RepeatMonthly:
+		if r.Day == 0 {
+			return r.Freq
+		}
+		return fmt.Sprintf("%s:%d", r.Freq, r.Day)
+	case RepeatEvery:
+		return fmt.Sprintf("%s:%d", r.Freq, r.Days)
+	default:
+		return r.Freq
+	}
+}
+
+// Returns the first occurrence after the given date
+func (r *Recurrence) Next(after time.Time) time.Time {
+	switch r.Freq {
+	case RepeatWeekly:
+		if len(r.Weekdays) == 0 {
+			return after.AddDate(0, 0, 7)
+		}
+		for d := 1; d <= 7; d++ {
+			next := after.AddDate(0, 0, d)
+			for _, wd := range r.Weekdays {
+				if next.Weekday() == wd {
+					return next
+				}
+			}
+		}
+		return after.AddDate(0, 0, 7)
+	case RepeatMonthly:
+		day := r.Day
+		if day == 0 {
+			day = after.Day()
+		}
+
+		// Days past the end of the next month would overflow into the one after
+		y, m, _ := after.Date()
+		first := time.Date(y, m+1, 1, after.Hour(), after.Minute(), after.Second(),
+			after.Nanosecond(), after.Location())
+		if last := first.AddDate(0, 1, -1).Day(); day > last {
+			day = last
+		}
+		return first.AddDate(0, 0, day-1)
+	case RepeatEvery:
+		return after.AddDate(0, 0, r.Days)
+	default:
+		return after.AddDate(0, 0, 1)
+	}
+}
+
+// Returns a copy of the rule that does not share slices with the original
+func (r *Recurrence) clone() *Recurrence {
+	if r == nil {
+		return nil
+	}
+
+	c := *r
+	c.Weekdays = append([]time.Weekday(nil), r.Weekdays...)
+	return &c
+}
+
+// Sets the recurrence rule of the item
+func WithRepeat(r *Recurrence) Option {
+	return func(t *item) {
+		t.Repeat = r.clone()
+	}
+}
+
+// Adds the next occurrence of a completed recurring item.
+// The due date follows the rule from the previous due date, or from
+// the completion date if there was none, and is never in the past.
+// Monthly rules keep the first day of the month, so the occurrences
+// after a shorter month go back to it.
+func (l *List) spawnNext(t *item) {
+	base := t.Due
+	if base.IsZero() {
+		base = t.CompletedAt
+	}
+
+	rule := t.Repeat.clone()
+	if rule.Freq == RepeatMonthly && rule.Day == 0 {
+		rule.Day = base.Day()
+	}
+
+	y, m, d := time.Now().Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, base.Location())
+	due := rule.Next(base)
+	for due.Before(today) {
+		due = rule.Next(due)
+	}
+
+	next := t.clone()
+	next.Repeat = rule
+	next.ID = l.nextID()
+	next.Done = false
+	next.CreatedAt = time.Now()
+	next.UpdatedAt = next.CreatedAt
+	next.CompletedAt = time.Time{}
+	next.Due = due
+	next.Reminded = UrgencyNone
+
+	l.Items = append(l.Items, next)
+}
@@ -0,0 +1,178 @@
+package todo_test
+
+import (
+	"errors"
+	"rggo/interacting/todo"
+	"testing"
+	"time"
+)
+
+// Tests parsing, formatting and the next date of the recurrence rules
+func TestRecurrence(t *testing.T) {
+	// Thursday
+	from := time.Date(2026, time.October, 15, 0, 0, 0, 0, time.Local)
+
+	testCases := []struct {
+		name    string
+		rule    string
+		expRule string
+		expNext time.Time
+		expErr  error
+	}{
+		{name: "Daily", rule: "daily", expRule: "daily", expNext: from.AddDate(0, 0, 1)},
+		{name: "Weekly", rule: "weekly", expRule: "weekly", expNext: from.AddDate(0, 0, 7)},
+		{name: "Weekdays", rule: "Weekly:Monday,fri", expRule: "weekly:mon,fri", expNext: from.AddDate(0, 0, 1)},
+		{name: "WeekdaysNextWeek", rule: "weekly:tue", expRule: "weekly:tue", expNext: from.AddDate(0, 0, 5)},
+		{name: "Monthly", rule: "monthly", expRule: "monthly", expNext: from.AddDate(0, 1, 0)},
+		{name: "MonthlyDay", rule: "monthly:20", expRule: "monthly:20", expNext: from.AddDate(0, 1, 5)},
+		{name: "InvalidMonthDay", rule: "monthly:32", expErr: todo.ErrInvalidRecurrence},
+		{name: "EveryNDays", rule: "every:3", expRule: "every:3", expNext: from.AddDate(0, 0, 3)},
+		{name: "InvalidDays", rule: "every:0", expErr: todo.ErrInvalidRecurrence},
+		{name: "InvalidWeekday", rule: "weekly:funday", expErr: todo.ErrInvalidRecurrence},
+		{name: "Unknown", rule: "yearly", expErr: todo.ErrInvalidRecurrence},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := todo.ParseRecurrence(tc.rule)
+			if tc.expErr != nil {
+				if !errors.Is(err, tc.expErr) {
+					t.Fatalf("Expected error %q, got %q.", tc.expErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %q.", err)
+			}
+
+			if r.String() != tc.expRule {
+				t.Errorf("Expected rule %q, got %q.", tc.expRule, r.String())
+			}
+			if next := r.Next(from); !next.Equal(tc.expNext) {
+				t.Errorf("Expected next date %s, got %s.", tc.expNext, next)
+			}
+		})
+	}
+}
+
+// Tests that monthly occurrences use the last day of shorter months
+// and go back to the day of the month after them
+func TestRecurrenceMonthEnd(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 9, 0, 0, 0, time.Local)
+	}
+
+	testCases := []struct {
+		name string
+		rule string
+		from time.Time
+		exp  []time.Time
+	}{
+		{name: "29th", rule: "monthly:29", from: date(2027, time.January, 29),
+			exp: []time.Time{date(2027, time.February, 28), date(2027, time.March, 29)}},
+		{name: "29thLeapYear", rule: "monthly:29", from: date(2028, time.January, 29),
+			exp: []time.Time{date(2028, time.February, 29), date(2028, time.March, 29)}},
+		{name: "30th", rule: "monthly:30", from: date(2027, time.January, 30),
+			exp: []time.Time{date(2027, time.February, 28), date(2027, time.March, 30)}},
+		{name: "31st", rule: "monthly:31", from: date(2027, time.January, 31),
+			exp: []time.Time{date(2027, time.February, 28), date(2027, time.March, 31),
+				date(2027, time.April, 30), date(2027, time.May, 31)}},
+		{name: "31stDecember", rule: "monthly:31", from: date(2027, time.December, 31),
+			exp: []time.Time{date(2028, time.January, 31), date(2028, time.February, 29)}},
+		{name: "DayOfTheDate", rule: "monthly", from: date(2027, time.January, 31),
+			exp: []time.Time{date(2027, time.February, 28), date(2027, time.March, 28)}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := todo.ParseRecurrence(tc.rule)
+			if err != nil {
+				t.Fatalf("Expected no error, got %q.", err)
+			}
+
+			next := tc.from
+			for _, exp := range tc.exp {
+				next = r.Next(next)
+				if !next.Equal(exp) {
+					t.Fatalf("Expected next date %s, got %s.", exp, next)
+				}
+			}
+		})
+	}
+}
+
+// Tests that completing a monthly item keeps the day of the month of its due date
+func TestCompleteRecurringMonthEnd(t *testing.T) {
+	l := todo.List{}
+	r, _ := todo.ParseRecurrence("monthly")
+	due := time.Date(2100, time.January, 31, 0, 0, 0, 0, time.Local)
+	l.Add("Pay rent", todo.WithRepeat(r), todo.WithDue(due))
+
+	l.Complete(1)
+	l.Complete(2)
+
+	expected := []time.Time{
+		due,
+		time.Date(2100, time.February, 28, 0, 0, 0, 0, time.Local),
+		time.Date(2100, time.March, 31, 0, 0, 0, 0, time.Local),
+	}
+	if len(l.Items) != len(expected) {
+		t.Fatalf("Expected %d items, got %d.", len(expected), len(l.Items))
+	}
+	for k, exp := range expected {
+		if !l.Items[k].Due.Equal(exp) {
+			t.Errorf("Expected due date %s for item %d, got %s.", exp, k+1, l.Items[k].Due)
+		}
+	}
+}
+
+// Tests that completing a recurring item adds the next occurrence
+func TestCompleteRecurring(t *testing.T) {
+	// Arrange
+	l := todo.List{}
+	r, _ := todo.ParseRecurrence("every:7")
+	y, m, d := time.Now().Date()
+	due := time.Date(y, m, d, 0, 0, 0, 0, time.Local).AddDate(0, 0, 1)
+	l.Add("Water plants", todo.WithRepeat(r), todo.WithDue(due), todo.WithTags("home"))
+
+	// Act
+	if err := l.Complete(1); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+	l.Complete(1)
+
+	// Assert
+	if len(l.Items) != 2 {
+		t.Fatalf("Expected 2 items, got %d.", len(l.Items))
+	}
+
+	next := l.Items[1]
+	if next.ID != 2 || next.Done || next.Task != "Water plants" {
+		t.Errorf("Expected open item 2 %q, got %v.", "Water plants", next)
+	}
+	if !next.Due.Equal(due.AddDate(0, 0, 7)) {
+		t.Errorf("Expected due date %s, got %s.", due.AddDate(0, 0, 7), next.Due)
+	}
+	if next.Repeat == nil || next.Repeat.String() != "every:7" || len(next.Tags) != 1 {
+		t.Errorf("Expected recurrence and tags to be copied, got %v.", next)
+	}
+
+	expected := "  2: Water plants due " + next.Due.Format(todo.DateFormat) +
+		" #home (repeats every:7)        \n"
+	assertString(expected, l.Print(false, true), t)
+}
+
+// Tests that the next occurrence of an overdue item is not in the past
+func TestCompleteRecurringOverdue(t *testing.T) {
+	l := todo.List{}
+	r, _ := todo.ParseRecurrence("daily")
+	l.Add("Stand-up", todo.WithRepeat(r), todo.WithDue(time.Now().AddDate(0, 0, -10)))
+
+	l.Complete(1)
+
+	y, m, d := time.Now().Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	if l.Items[1].Due.Before(today) {
+		t.Errorf("Expected due date not before %s, got %s.", today, l.Items[1].Due)
+	}
+}
@@ -0,0 +1,88 @@
+package todo
+
+import (
+	"fmt"
+	"time"
+)
+
+// How close an open item is to its due date
+type Urgency int
+
+const (
+	UrgencyNone Urgency = iota
+	// Due within the reminder window
+	UrgencySoon
+	// Due today
+	UrgencyToday
+	// Past the due date
+	UrgencyOverdue
+)
+
+func (u Urgency) String() string {
+	switch u {
+	case UrgencySoon:
+		return "due soon"
+	case UrgencyToday:
+		return "due today"
+	case UrgencyOverdue:
+		return "overdue"
+	default:
+		return "not due"
+	}
+}
+
+// Notice about an item that became more urgent
+type Reminder struct {
+	ID      int
+	Task    string
+	Due     time.Time
+	Urgency Urgency
+}
+
+func (r Reminder) String() string {
+	return fmt.Sprintf("%d: %s is %s (due %s)", r.ID, r.Task, r.Urgency, r.Due.Format(DateFormat))
+}
+
+// Urgency of the item at the time. Items are due on the whole due date.
+func urgency(t *item, now time.Time, soon time.Duration) Urgency {
+	switch {
+	case t.Done || t.Due.IsZero():
+		return UrgencyNone
+	case !now.Before(t.Due.AddDate(0, 0, 1)):
+		return UrgencyOverdue
+	case !now.Before(t.Due):
+		return UrgencyToday
+	case !now.Before(t.Due.Add(-soon)):
+		return UrgencySoon
+	default:
+		return UrgencyNone
+	}
+}
+
+// Open items due within soon or overdue that became more urgent since their last reminder
+func (l *List) Reminders(now time.Time, soon time.Duration) []Reminder {
+	reminders := []Reminder{}
+	for k := range l.Items {
+		t := &l.Items[k]
+
+		u := urgency(t, now, soon)
+		if u == UrgencyNone || u <= t.Reminded {
+			continue
+		}
+
+		reminders = append(reminders, Reminder{ID: t.ID, Task: t.Task, Due: t.Due, Urgency: u})
+	}
+
+	return reminders
+}
+
+// Records that the reminder was sent, so it is repeated only when the item gets more urgent
+func (l *List) MarkReminded(r Reminder) error {
+	idx, err := l.Index(r.ID)
+	if err != nil {
+		return err
+	}
+
+	l.Items[idx].Reminded = r.Urgency
+	return nil
+}
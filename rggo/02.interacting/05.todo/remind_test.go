@@ -0,0 +1,113 @@
+package todo_test
+
+import (
+	"testing"
+	"time"
+
+	"rggo/interacting/todo"
+)
+
+// Tests that reminders escalate as the due date passes and are not repeated
+func TestReminders(t *testing.T) {
+	due := time.Date(2026, time.November, 10, 0, 0, 0, 0, time.Local)
+
+	l := todo.List{}
+	l.Add("Due task", todo.WithDue(due))
+	l.Add("No due date")
+	l.Add("Done task", todo.WithDue(due))
+	l.Complete(3)
+
+	testCases := []struct {
+		name     string
+		now      time.Time
+		expected todo.Urgency
+	}{
+		{name: "NotYet", now: due.Add(-48 * time.Hour), expected: todo.UrgencyNone},
+		{name: "Soon", now: due.Add(-12 * time.Hour), expected: todo.UrgencySoon},
+		{name: "SoonRepeated", now: due.Add(-6 * time.Hour), expected: todo.UrgencyNone},
+		{name: "Today", now: due.Add(9 * time.Hour), expected: todo.UrgencyToday},
+		{name: "Overdue", now: due.AddDate(0, 0, 1), expected: todo.UrgencyOverdue},
+		{name: "OverdueRepeated", now: due.AddDate(0, 0, 3), expected: todo.UrgencyNone},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reminders := l.Reminders(tc.now, 24*time.Hour)
+
+			if tc.expected == todo.UrgencyNone {
+				if len(reminders) != 0 {
+					t.Fatalf("Expected no reminders, got %v.", reminders)
+				}
+				return
+			}
+
+			if len(reminders) != 1 {
+				t.Fatalf("Expected 1 reminder, got %v.", reminders)
+			}
+			if reminders[0].ID != 1 || reminders[0].Urgency != tc.expected {
+				t.Errorf("Expected item 1 %s, got %v.", tc.expected, reminders[0])
+			}
+
+			if err := l.MarkReminded(reminders[0]); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// Tests that moving the due date of a reminded item reminds it again
+func TestRemindersMovedDue(t *testing.T) {
+	due := time.Date(2026, time.November, 10, 0, 0, 0, 0, time.Local)
+
+	l := todo.List{}
+	l.Add("Due task", todo.WithDue(due))
+	l.MarkReminded(l.Reminders(due.AddDate(0, 0, 1), time.Hour)[0])
+
+	moved := due.AddDate(0, 0, 7)
+	if err := l.SetDetails(1, todo.WithDue(moved)); err != nil {
+		t.Fatal(err)
+	}
+
+	reminders := l.Reminders(moved.Add(time.Hour), time.Hour)
+	if len(reminders) != 1 || reminders[0].Urgency != todo.UrgencyToday {
+		t.Fatalf("Expected item 1 %s, got %v.", todo.UrgencyToday, reminders)
+	}
+
+	// Setting the same due date keeps the reminder sent
+	l.MarkReminded(reminders[0])
+	l.SetDetails(1, todo.WithDue(moved))
+	if reminders := l.Reminders(moved.Add(time.Hour), time.Hour); len(reminders) != 0 {
+		t.Errorf("Expected no reminders, got %v.", reminders)
+	}
+}
+
+// Tests that sent reminders are not recorded as operations to undo
+func TestRemindedNotRecorded(t *testing.T) {
+	due := time.Now().AddDate(0, 0, -2)
+
+	before := todo.List{}
+	before.Add("Due task", todo.WithDue(due))
+
+	after := before.Clone()
+	after.MarkReminded(after.Reminders(time.Now(), time.Hour)[0])
+
+	h := todo.History{}
+	h.Record(before, after)
+	if len(h.Entries) != 0 {
+		t.Errorf("Expected no history entries, got %d.", len(h.Entries))
+	}
+}
+
+func TestReminderString(t *testing.T) {
+	r := todo.Reminder{
+		ID:      2,
+		Task:    "Pay rent",
+		Due:     time.Date(2026, time.November, 1, 0, 0, 0, 0, time.Local),
+		Urgency: todo.UrgencyOverdue,
+	}
+
+	expected := "2: Pay rent is overdue (due 2026-11-01)"
+	if r.String() != expected {
+		t.Errorf("Expected %q, got %q.", expected, r.String())
+	}
+}
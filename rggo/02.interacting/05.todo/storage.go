@@ -0,0 +1,104 @@
+package todo
+
+// Storage loads and saves a whole todo list.
+// Implementations are in the storage package.
+type Storage interface {
+	// Reads the list from the store. A missing store gives an empty list.
+	Load(l *List) error
+	// Replaces the stored list with the given one
+	Save(l *List) error
+}
+
+// Storage that can be locked for a whole Load-modify-Save cycle,
+// so several processes can share it safely
+type Locker interface {
+	Lock() error
+	Unlock() error
+}
+
+// Storage with several named lists (projects).
+// The storage itself holds the DefaultProject list.
+type ProjectStorage interface {
+	Storage
+	// Returns the storage of the named list. A new name gives an empty list.
+	Project(name string) (Storage, error)
+	// Names of the stored projects in alphabetical order, DefaultProject included
+	Projects() ([]string, error)
+}
+
+// Storage that keeps the history of operations next to the list
+type HistoryStorage interface {
+	LoadHistory(h *History) error
+	SaveHistory(h *History) error
+}
+
+// Loads the list from the storage, applies fn and saves the result.
+// The storage is locked for the whole cycle if it supports locking,
+// and the changes are recorded if it keeps the history.
+// Nothing is saved if fn returns an error.
+func Update(s Storage, fn func(l *List) error) error {
+	return update(s, func(l *List, h *History) error {
+		before := l.Clone()
+		if err := fn(l); err != nil {
+			return err
+		}
+
+		h.Record(before, *l)
+		return nil
+	})
+}
+
+// Reverts the last operation recorded in the storage
+func Undo(s Storage) error {
+	return update(s, func(l *List, h *History) error {
+		return l.Undo(h)
+	})
+}
+
+// Applies again the last operation undone in the storage
+func Redo(s Storage) error {
+	return update(s, func(l *List, h *History) error {
+		return l.Redo(h)
+	})
+}
+
+func update(s Storage, fn func(l *List, h *History) error) error {
+	if lk, ok := s.(Locker); ok {
+		if err := lk.Lock(); err != nil {
+			return err
+		}
+		defer lk.Unlock()
+	}
+
+	return modify(s, fn)
+}
+
+// Loads the list and the history, applies fn and saves them without locking
+func modify(s Storage, fn func(l *List, h *History) error) error {
+	l := &List{}
+	if err := s.Load(l); err != nil {
+		return err
+	}
+
+	h := &History{}
+	hs, keepsHistory := s.(HistoryStorage)
+	if keepsHistory {
+		if err := hs.LoadHistory(h); err != nil {
+			return err
+		}
+	}
+
+	if err := fn(l, h); err != nil {
+		return err
+	}
+
+	if err := s.Save(l); err != nil {
+		return err
+	}
+
+	if keepsHistory {
+		return hs.SaveHistory(h)
+	}
+
+	return nil
+}
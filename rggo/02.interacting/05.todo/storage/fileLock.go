@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"sync"
+
+	"rggo/interacting/todo"
+)
+
+// Advisory lock on a file next to the stored data, shared by file based stores
+type fileLocker struct {
+	path string
+	mu   sync.Mutex
+	lock *todo.FileLock
+}
+
+// Blocks other processes and goroutines until Unlock is called
+func (fl *fileLocker) Lock() error {
+	fl.mu.Lock()
+
+	lock, err := todo.LockFile(fl.path, todo.DefaultLockTimeout)
+	if err != nil {
+		fl.mu.Unlock()
+		return err
+	}
+
+	fl.lock = lock
+	return nil
+}
+
+func (fl *fileLocker) Unlock() error {
+	defer fl.mu.Unlock()
+
+	err := fl.lock.Unlock()
+	fl.lock = nil
+	return err
+}
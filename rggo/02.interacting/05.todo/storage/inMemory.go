@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"encoding/json"
+	"sort"
+	"sync" // To prevent conflicts when executing this code concurrently
+
+	"rggo/interacting/todo"
+)
+
+// In-memory storage. Useful for tests.
+type inMemory struct {
+	// Shared by all projects of the storage
+	*memoryData
+	project string
+}
+
+// Lists and histories of all projects
+type memoryData struct {
+	// To prevent concurrent access to the data store
+	sync.RWMutex
+	lists      map[string]todo.List
+	histories  map[string][]byte
+	syncStates map[string][]byte
+	// Archived items, nil in the archive itself
+	archive *memoryData
+}
+
+// Initiate a new in-memory storage
+func NewInMemory() *inMemory {
+	return &inMemory{
+		memoryData: &memoryData{
+			lists:      map[string]todo.List{todo.DefaultProject: {}},
+			histories:  map[string][]byte{},
+			syncStates: map[string][]byte{},
+			archive: &memoryData{
+				lists:      map[string]todo.List{todo.DefaultProject: {}},
+				histories:  map[string][]byte{},
+				syncStates: map[string][]byte{},
+			},
+		},
+		project: todo.DefaultProject,
+	}
+}
+
+func (s *inMemory) Load(l *todo.List) error {
+	s.RLock()
+	defer s.RUnlock()
+
+	*l = s.lists[s.project].Clone()
+	return nil
+}
+
+// Replaces the list of the project. Empty projects other than the default are removed.
+func (s *inMemory) Save(l *todo.List) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(l.Items) == 0 && s.project != todo.DefaultProject {
+		delete(s.lists, s.project)
+		return nil
+	}
+
+	s.lists[s.project] = l.Clone()
+	return nil
+}
+
+func (s *inMemory) Project(name string) (todo.Storage, error) {
+	if err := todo.ValidateProject(name); err != nil {
+		return nil, err
+	}
+
+	return &inMemory{memoryData: s.memoryData, project: name}, nil
+}
+
+func (s *inMemory) Archive() (todo.Storage, error) {
+	if s.archive == nil {
+		return nil, todo.ErrNoArchive
+	}
+
+	return &inMemory{memoryData: s.archive, project: s.project}, nil
+}
+
+func (s *inMemory) Projects() ([]string, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	names := make([]string, 0, len(s.lists))
+	for name := range s.lists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (s *inMemory) LoadHistory(h *todo.History) error {
+	s.RLock()
+	defer s.RUnlock()
+
+	js, ok := s.histories[s.project]
+	if !ok {
+		return nil
+	}
+
+	// Decoding gives a copy that does not share items with the store
+	return json.Unmarshal(js, h)
+}
+
+func (s *inMemory) SaveHistory(h *todo.History) error {
+	s.Lock()
+	defer s.Unlock()
+
+	js, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+
+	s.histories[s.project] = js
+	return nil
+}
+
+func (s *inMemory) LoadSyncState(st *todo.SyncState) error {
+	s.RLock()
+	defer s.RUnlock()
+
+	js, ok := s.syncStates[s.project]
+	if !ok {
+		return nil
+	}
+
+	return json.Unmarshal(js, st)
+}
+
+func (s *inMemory) SaveSyncState(st *todo.SyncState) error {
+	s.Lock()
+	defer s.Unlock()
+
+	js, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	s.syncStates[s.project] = js
+	return nil
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"rggo/interacting/todo"
+)
+
+// Stores the lists of all projects as JSON in a single file.
+// The lock, the history, the archived items and the sync state are kept
+// in separate files with the ".lock", ".history", ".archive" and ".sync" suffixes.
+// With a cipher all of them but the lock are encrypted.
+type jsonFile struct {
+	// Shared by all projects of the file
+	*fileLocker
+	cipher   *todo.Cipher
+	filename string
+	project  string
+}
+
+// Initiate a new JSON file storage
+func NewJSONFile(filename string) *jsonFile {
+	return &jsonFile{
+		fileLocker: &fileLocker{path: filename + ".lock"},
+		filename:   filename,
+		project:    todo.DefaultProject,
+	}
+}
+
+// Initiate a new JSON file storage encrypted with the cipher.
+// Plain files are not read; Encrypt encrypts them first.
+func NewEncryptedJSONFile(filename string, c *todo.Cipher) *jsonFile {
+	s := NewJSONFile(filename)
+	s.cipher = c
+	return s
+}
+
+func (s *jsonFile) Load(l *todo.List) error {
+	p := todo.Projects{}
+	if err := p.GetWith(s.filename, s.cipher); err != nil {
+		return err
+	}
+
+	if pl, ok := p[s.project]; ok {
+		*l = pl
+	} else {
+		*l = todo.List{}
+	}
+
+	return nil
+}
+
+// Replaces the list of the project. Empty projects other than the default are removed.
+func (s *jsonFile) Save(l *todo.List) error {
+	p := todo.Projects{}
+	if err := p.GetWith(s.filename, s.cipher); err != nil {
+		return err
+	}
+
+	if len(l.Items) == 0 && s.project != todo.DefaultProject {
+		delete(p, s.project)
+	} else {
+		p[s.project] = *l
+	}
+
+	return p.SaveWith(s.filename, s.cipher)
+}
+
+func (s *jsonFile) Project(name string) (todo.Storage, error) {
+	if err := todo.ValidateProject(name); err != nil {
+		return nil, err
+	}
+
+	return &jsonFile{
+		fileLocker: s.fileLocker,
+		cipher:     s.cipher,
+		filename:   s.filename,
+		project:    name,
+	}, nil
+}
+
+// Archived items are not loaded with the list, as they are in another file
+func (s *jsonFile) Archive() (todo.Storage, error) {
+	return &jsonFile{
+		fileLocker: s.fileLocker,
+		cipher:     s.cipher,
+		filename:   s.filename + ".archive",
+		project:    s.project,
+	}, nil
+}
+
+func (s *jsonFile) Projects() ([]string, error) {
+	p := todo.Projects{}
+	if err := p.GetWith(s.filename, s.cipher); err != nil {
+		return nil, err
+	}
+
+	if _, ok := p[todo.DefaultProject]; !ok {
+		p[todo.DefaultProject] = todo.List{}
+	}
+
+	return p.Names(), nil
+}
+
+func (s *jsonFile) LoadHistory(h *todo.History) error {
+	return h.GetWith(s.projectFile(".history"), s.cipher)
+}
+
+func (s *jsonFile) SaveHistory(h *todo.History) error {
+	return h.SaveWith(s.projectFile(".history"), s.cipher)
+}
+
+func (s *jsonFile) LoadSyncState(st *todo.SyncState) error {
+	return st.GetWith(s.projectFile(".sync"), s.cipher)
+}
+
+func (s *jsonFile) SaveSyncState(st *todo.SyncState) error {
+	return st.SaveWith(s.projectFile(".sync"), s.cipher)
+}
+
+// Name of a file kept for the project only.
+// The default project keeps the file names of a single list.
+func (s *jsonFile) projectFile(suffix string) string {
+	if s.project == todo.DefaultProject {
+		return s.filename + suffix
+	}
+
+	return s.filename + "." + s.project + suffix
+}
+
+// Encrypts the plain files of the storage: the lists, and the history, archive
+// and sync state of every project. Returns the number of encrypted files.
+func (s *jsonFile) encrypt() (int, error) {
+	if err := s.Lock(); err != nil {
+		return 0, err
+	}
+	defer s.Unlock()
+
+	dir := filepath.Dir(s.filename)
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, err
+	}
+
+	files := []string{s.filename, s.filename + ".archive"}
+	prefix := filepath.Base(s.filename) + "."
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, prefix) &&
+			(strings.HasSuffix(name, ".history") || strings.HasSuffix(name, ".sync")) {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+
+	n := 0
+	for _, f := range files {
+		encrypted, err := s.cipher.EncryptFile(f)
+		if err != nil {
+			return n, err
+		}
+		if encrypted {
+			n++
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"rggo/interacting/todo"
+)
+
+// Returned when the server does not accept a request
+var ErrRemote = errors.New("remote request failed")
+
+// List served by todoServer. It is loaded and replaced as a whole,
+// so it is meant to sync with a local list. Save only replaces the
+// version given by the last Load or Save and fails with
+// todo.ErrRemoteChanged if the list changed since.
+type remote struct {
+	url    string
+	client *http.Client
+	// Entity tag of the last loaded or saved version
+	etag string
+}
+
+// Initiate the storage of the project list served at the base URL like http://host:8080.
+// A nil client uses a default one with a timeout.
+func NewRemote(baseURL, project string, client *http.Client) *remote {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	url := strings.TrimRight(baseURL, "/") + "/todo"
+	if project != todo.DefaultProject {
+		url = strings.TrimRight(baseURL, "/") + "/projects/" + project + "/todo"
+	}
+
+	return &remote{url: url, client: client}
+}
+
+// Client sending the bearer token with every request,
+// for servers that require authentication
+func NewTokenClient(token string) *http.Client {
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &bearerTransport{token: token, next: http.DefaultTransport},
+	}
+}
+
+// Transport adding the Authorization header to the requests
+type bearerTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (t *bearerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	// Round trippers must not change the request
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+t.token)
+	return t.next.RoundTrip(r)
+}
+
+// URL of the list, which names it in the sync state
+func (s *remote) String() string {
+	return s.url
+}
+
+func (s *remote) Load(l *todo.List) error {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: GET %s: %s", ErrRemote, s.url, resp.Status)
+	}
+
+	body := struct {
+		Results todo.List `json:"results"`
+		NextID  int       `json:"next_id"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return err
+	}
+
+	*l = body.Results
+	l.NextID = body.NextID
+	s.etag = resp.Header.Get("ETag")
+
+	return nil
+}
+
+// Replaces the served list if it did not change since it was loaded
+func (s *remote) Save(l *todo.List) error {
+	js, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, s.url, bytes.NewReader(js))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.etag != "" {
+		req.Header.Set("If-Match", s.etag)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return fmt.Errorf("%w: %w: PUT %s: %s", ErrRemote, todo.ErrRemoteChanged, s.url, resp.Status)
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("%w: PUT %s: %s", ErrRemote, s.url, resp.Status)
+	}
+
+	s.etag = resp.Header.Get("ETag")
+	return nil
+}
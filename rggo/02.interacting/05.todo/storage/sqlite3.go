@@ -0,0 +1,529 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"rggo/interacting/todo"
+
+	// Blank import for sqlite3 driver only
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	// Items of the lists and archived items have the same columns
+	createTableItems string = `CREATE TABLE IF NOT EXISTS "%s" (
+		"project" TEXT NOT NULL DEFAULT 'default',
+		"id" INTEGER,
+		"position" INTEGER NOT NULL,
+		"task" TEXT NOT NULL,
+		"done" BOOLEAN DEFAULT 0,
+		"created_at" DATETIME NOT NULL,
+		"completed_at" DATETIME,
+		"priority" TEXT DEFAULT '',
+		"due" DATETIME,
+		"tags" TEXT DEFAULT '',
+		"parent" INTEGER DEFAULT 0,
+		"blocked_by" TEXT DEFAULT '',
+		"repeat" TEXT DEFAULT '',
+		"reminded" INTEGER DEFAULT 0,
+		"updated_at" DATETIME,
+		"notes" TEXT DEFAULT '',
+		"attachments" TEXT DEFAULT '',
+		PRIMARY KEY ("project", "id")
+	);`
+
+	// The whole history of a project is kept as JSON in a single row
+	createTableHistory string = `CREATE TABLE IF NOT EXISTS "history" (
+		"project" TEXT NOT NULL,
+		"data" TEXT NOT NULL,
+		PRIMARY KEY ("project")
+	);`
+
+	// Sync state of a project as JSON, like the history
+	createTableSyncState string = `CREATE TABLE IF NOT EXISTS "sync_state" (
+		"project" TEXT NOT NULL,
+		"data" TEXT NOT NULL,
+		PRIMARY KEY ("project")
+	);`
+
+	// ID of the next new item of each list of items, so IDs of removed items are not given again
+	createTableListMeta string = `CREATE TABLE IF NOT EXISTS "list_meta" (
+		"list" TEXT NOT NULL,
+		"project" TEXT NOT NULL,
+		"next_id" INTEGER NOT NULL,
+		PRIMARY KEY ("list", "project")
+	);`
+
+	// Separator of the values in list columns like "blocked_by"
+	listSeparator = ","
+
+	// Tables of the items
+	tableItem    = "item"
+	tableArchive = "archive"
+)
+
+// Columns added to the tables of items after their first version.
+// Databases created before get them on open.
+var itemColumns = []struct {
+	name       string
+	definition string
+}{
+	{name: "parent", definition: `"parent" INTEGER DEFAULT 0`},
+	{name: "blocked_by", definition: `"blocked_by" TEXT DEFAULT ''`},
+	{name: "repeat", definition: `"repeat" TEXT DEFAULT ''`},
+	{name: "reminded", definition: `"reminded" INTEGER DEFAULT 0`},
+	{name: "updated_at", definition: `"updated_at" DATETIME`},
+	{name: "notes", definition: `"notes" TEXT DEFAULT ''`},
+	{name: "attachments", definition: `"attachments" TEXT DEFAULT ''`},
+}
+
+// Tables of databases created before projects, rebuilt with the project in the key.
+// The old rows go to the default project.
+var projectTables = []struct {
+	name    string
+	create  string
+	columns string
+}{
+	{
+		name:   tableItem,
+		create: fmt.Sprintf(createTableItems, tableItem),
+		columns: `id, position, task, done, created_at, completed_at,
+			priority, due, tags, parent, blocked_by, repeat, reminded, updated_at,
+			notes, attachments`,
+	},
+	{name: "history", create: createTableHistory, columns: "data"},
+}
+
+type dbStore struct {
+	// Shared by all projects of the database
+	*database
+	project string
+	table   string
+}
+
+type database struct {
+	fileLocker
+	db *sql.DB
+	// To prevent concurrent access within the process
+	mu sync.RWMutex
+}
+
+// Initiate a new SQLite storage in the given database file
+func NewSQLite3(dbfile string) (*dbStore, error) {
+	db, err := sql.Open("sqlite3", dbfile)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetConnMaxLifetime(30 * time.Minute)
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	for _, stmt := range []string{
+		fmt.Sprintf(createTableItems, tableItem),
+		fmt.Sprintf(createTableItems, tableArchive),
+		createTableHistory,
+		createTableSyncState,
+		createTableListMeta,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := migrate(db); err != nil {
+		return nil, err
+	}
+
+	return &dbStore{
+		database: &database{
+			fileLocker: fileLocker{path: dbfile + ".lock"},
+			db:         db,
+		},
+		project: todo.DefaultProject,
+		table:   tableItem,
+	}, nil
+}
+
+// Bring databases created by older versions to the current schema
+func migrate(db *sql.DB) error {
+	for _, table := range []string{tableItem, tableArchive} {
+		existing, err := tableColumns(db, table)
+		if err != nil {
+			return err
+		}
+
+		for _, c := range itemColumns {
+			if existing[c.name] {
+				continue
+			}
+			if _, err := db.Exec("ALTER TABLE " + table + " ADD COLUMN " + c.definition); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, t := range projectTables {
+		existing, err := tableColumns(db, t.name)
+		if err != nil {
+			return err
+		}
+		if existing["project"] {
+			continue
+		}
+		if err := addProjectKey(db, t.name, t.create, t.columns); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Names of the columns of the table
+func tableColumns(db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+
+	return columns, rows.Err()
+}
+
+// Recreate the table with the project column and copy the rows into the default project.
+// SQLite cannot change the primary key of an existing table.
+func addProjectKey(db *sql.DB, table, create, columns string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	old := table + "_old"
+	stmts := []string{
+		"ALTER TABLE " + table + " RENAME TO " + old,
+		create,
+		"INSERT INTO " + table + " (project, " + columns + ") " +
+			"SELECT '" + todo.DefaultProject + "', " + columns + " FROM " + old,
+		"DROP TABLE " + old,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *dbStore) Project(name string) (todo.Storage, error) {
+	if err := todo.ValidateProject(name); err != nil {
+		return nil, err
+	}
+
+	return &dbStore{database: s.database, project: name, table: s.table}, nil
+}
+
+// Archived items of the project are kept in a table of their own
+func (s *dbStore) Archive() (todo.Storage, error) {
+	return &dbStore{database: s.database, project: s.project, table: tableArchive}, nil
+}
+
+func (s *dbStore) Projects() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(
+		`SELECT DISTINCT project FROM item
+		UNION SELECT ?
+		ORDER BY 1`, todo.DefaultProject)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// Read all items of the project in their list order
+func (s *dbStore) Load(l *todo.List) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(
+		`SELECT id, task, done, created_at, completed_at, priority, due, tags,
+			parent, blocked_by, repeat, reminded, updated_at, notes, attachments
+		FROM `+s.table+` WHERE project = ? ORDER BY position`, s.project)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// Items are scanned in place as the item type is not exported
+	list := todo.List{}
+	for rows.Next() {
+		list.Items = appendZero(list.Items)
+		t := &list.Items[len(list.Items)-1]
+
+		var tags, blockedBy, repeat, attachments string
+		// Rows saved before the column was added have no value
+		var updatedAt sql.NullTime
+		err := rows.Scan(
+			&t.ID, &t.Task, &t.Done, &t.CreatedAt, &t.CompletedAt,
+			&t.Priority, &t.Due, &tags, &t.Parent, &blockedBy, &repeat, &t.Reminded,
+			&updatedAt, &t.Notes, &attachments)
+		if err != nil {
+			return err
+		}
+		t.UpdatedAt = updatedAt.Time
+
+		if t.Tags, err = parseTags(tags); err != nil {
+			return err
+		}
+
+		if t.BlockedBy, err = parseIDs(blockedBy); err != nil {
+			return err
+		}
+
+		if repeat != "" {
+			if t.Repeat, err = todo.ParseRecurrence(repeat); err != nil {
+				return err
+			}
+		}
+
+		if attachments != "" {
+			if err := json.Unmarshal([]byte(attachments), &t.Attachments); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	err = s.db.QueryRow("SELECT next_id FROM list_meta WHERE list = ? AND project = ?",
+		s.table, s.project).Scan(&list.NextID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	*l = list
+	return nil
+}
+
+// Appends a zero element to a slice of a type that is not exported
+func appendZero[S ~[]E, E any](s S) S {
+	var zero E
+	return append(s, zero)
+}
+
+// Replace all stored items of the project with the list in a single transaction
+func (s *dbStore) Save(l *todo.List) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM "+s.table+" WHERE project = ?", s.project); err != nil {
+		return err
+	}
+
+	// Prepare INSERT statement
+	insStmt, err := tx.Prepare(
+		`INSERT INTO ` + s.table + ` (project, id, position, task, done, created_at, completed_at,
+			priority, due, tags, parent, blocked_by, repeat, reminded, updated_at,
+			notes, attachments)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insStmt.Close()
+
+	for pos, t := range l.Items {
+		repeat := ""
+		if t.Repeat != nil {
+			repeat = t.Repeat.String()
+		}
+
+		// Attachments have several fields, so they are kept as JSON
+		attachments := ""
+		if len(t.Attachments) > 0 {
+			js, err := json.Marshal(t.Attachments)
+			if err != nil {
+				return err
+			}
+			attachments = string(js)
+		}
+
+		tags, err := formatTags(t.Tags)
+		if err != nil {
+			return err
+		}
+
+		_, err = insStmt.Exec(
+			s.project, t.ID, pos, t.Task, t.Done, t.CreatedAt, t.CompletedAt,
+			t.Priority, t.Due, tags,
+			t.Parent, formatIDs(t.BlockedBy), repeat, t.Reminded, t.UpdatedAt,
+			t.Notes, attachments)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("INSERT OR REPLACE INTO list_meta (list, project, next_id) VALUES(?, ?, ?)",
+		s.table, s.project, l.NextID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Read the history of operations
+func (s *dbStore) LoadHistory(h *todo.History) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var data string
+	err := s.db.QueryRow("SELECT data FROM history WHERE project = ?", s.project).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(data), h)
+}
+
+// Replace the history of operations
+func (s *dbStore) SaveHistory(h *todo.History) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec("INSERT OR REPLACE INTO history (project, data) VALUES(?, ?)",
+		s.project, string(data))
+	return err
+}
+
+// Read the times of the last syncs
+func (s *dbStore) LoadSyncState(st *todo.SyncState) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var data string
+	err := s.db.QueryRow("SELECT data FROM sync_state WHERE project = ?", s.project).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(data), st)
+}
+
+// Replace the times of the last syncs
+func (s *dbStore) SaveSyncState(st *todo.SyncState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec("INSERT OR REPLACE INTO sync_state (project, data) VALUES(?, ?)",
+		s.project, string(data))
+	return err
+}
+
+// Formats tags as a JSON array for a TEXT column, as tags may have commas
+func formatTags(tags []string) (string, error) {
+	if len(tags) == 0 {
+		return "", nil
+	}
+
+	js, err := json.Marshal(tags)
+	return string(js), err
+}
+
+// Parses tags from a JSON array in a TEXT column.
+// Rows saved by older versions have comma separated tags.
+func parseTags(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	tags := []string{}
+	if strings.HasPrefix(s, "[") && json.Unmarshal([]byte(s), &tags) == nil {
+		return tags, nil
+	}
+
+	return strings.Split(s, listSeparator), nil
+}
+
+// Formats IDs as a comma separated list for a TEXT column
+func formatIDs(ids []int) string {
+	s := make([]string, len(ids))
+	for k, id := range ids {
+		s[k] = strconv.Itoa(id)
+	}
+
+	return strings.Join(s, listSeparator)
+}
+
+// Parses IDs from a comma separated list in a TEXT column
+func parseIDs(s string) ([]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	ids := []int{}
+	for _, f := range strings.Split(s, listSeparator) {
+		id, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"rggo/interacting/todo"
+)
+
+var (
+	// Returned when the storage URI has an unknown scheme
+	ErrUnknownScheme = errors.New("unknown storage scheme")
+	// Returned when encryption is asked for a storage that does not support it
+	ErrNoEncryption = errors.New("storage does not support encryption")
+)
+
+// Storage URI schemes
+const (
+	SchemeFile   = "file"
+	SchemeSQLite = "sqlite"
+	SchemeMemory = "memory"
+)
+
+// Settings of the storage opened by New
+type options struct {
+	cipher *todo.Cipher
+}
+
+// Setting of the storage opened by New
+type Option func(o *options)
+
+// Encrypts the storage with the cipher. Only JSON files support encryption.
+// A nil cipher leaves the storage plain.
+func WithCipher(c *todo.Cipher) Option {
+	return func(o *options) {
+		o.cipher = c
+	}
+}
+
+// Opens the storage described by the URI:
+//
+//	path/to/todo.json, file://path/to/todo.json - JSON file
+//	sqlite:///path/to/todo.db                   - SQLite database
+//	memory://                                   - in-memory list
+func New(uri string, opts ...Option) (todo.ProjectStorage, error) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	scheme, path, found := strings.Cut(uri, "://")
+	if !found {
+		return NewEncryptedJSONFile(uri, o.cipher), nil
+	}
+
+	if o.cipher != nil && scheme != SchemeFile {
+		return nil, fmt.Errorf("%w: %q", ErrNoEncryption, scheme)
+	}
+
+	switch scheme {
+	case SchemeFile:
+		return NewEncryptedJSONFile(path, o.cipher), nil
+	case SchemeSQLite:
+		return NewSQLite3(path)
+	case SchemeMemory:
+		return NewInMemory(), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownScheme, scheme)
+	}
+}
+
+// Encrypts the plain files of the JSON file storage of the URI with the cipher,
+// so it can be opened with it. Files already encrypted are left as they are.
+// Returns the number of encrypted files.
+func Encrypt(uri string, c *todo.Cipher) (int, error) {
+	if c == nil {
+		return 0, todo.ErrEmptyKey
+	}
+
+	s, err := New(uri, WithCipher(c))
+	if err != nil {
+		return 0, err
+	}
+
+	js, ok := s.(*jsonFile)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrNoEncryption, uri)
+	}
+
+	return js.encrypt()
+}
@@ -0,0 +1,583 @@
+package storage_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"rggo/interacting/todo"
+	"rggo/interacting/todo/storage"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Tests saving and loading a list with every storage backend
+func TestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	testCases := []struct {
+		name string
+		uri  string
+	}{
+		{name: "JSONFile", uri: filepath.Join(dir, "todo.json")},
+		{name: "JSONFileURI", uri: "file://" + filepath.Join(dir, "todo2.json")},
+		{name: "SQLite", uri: "sqlite://" + filepath.Join(dir, "todo.db")},
+		{name: "InMemory", uri: "memory://"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			store, err := storage.New(tc.uri)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			empty := todo.List{}
+			if err := store.Load(&empty); err != nil {
+				t.Fatalf("Expected no error loading empty store, got %q.", err)
+			}
+			if len(empty.Items) != 0 {
+				t.Fatalf("Expected empty list, got %d items.", len(empty.Items))
+			}
+
+			due := time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)
+			l1 := todo.List{}
+			l1.Add("Task 1", todo.WithTags("ops", "infra"), todo.WithDue(due))
+			l1.Add("Task 2", todo.WithPriority(todo.PriorityHigh), todo.WithTags("a,b", "c"))
+			l1.Add("Task 3")
+			r, _ := todo.ParseRecurrence("weekly:mon,thu")
+			l1.Add("Task 4", todo.WithRepeat(r))
+			l1.SetParent(3, 2)
+			l1.Block(2, 4)
+			l1.Delete(1)
+			l1.Complete(3)
+			l1.SetNotes(2, "Line 1\nLine 2")
+			l1.Attach(2, todo.Attachment{Path: "/tmp/a.txt", Size: 6, SHA256: "5891b5b522d5"})
+
+			// Act
+			if err := store.Save(&l1); err != nil {
+				t.Fatalf("Error saving list: %s", err)
+			}
+
+			l2 := todo.List{}
+			if err := store.Load(&l2); err != nil {
+				t.Fatalf("Error loading list: %s", err)
+			}
+
+			// Assert
+			if len(l2.Items) != len(l1.Items) {
+				t.Fatalf("Expected %d items, got %d.", len(l1.Items), len(l2.Items))
+			}
+			for k := range l1.Items {
+				if l1.Items[k].ID != l2.Items[k].ID || l1.Items[k].Task != l2.Items[k].Task || l1.Items[k].Done != l2.Items[k].Done {
+					t.Errorf("Expected item %v, got %v.", l1.Items[k], l2.Items[k])
+				}
+				if l1.Items[k].Priority != l2.Items[k].Priority {
+					t.Errorf("Expected priority %q, got %q.", l1.Items[k].Priority, l2.Items[k].Priority)
+				}
+				if fmt.Sprintf("%q", l1.Items[k].Tags) != fmt.Sprintf("%q", l2.Items[k].Tags) {
+					t.Errorf("Expected tags %q, got %q.", l1.Items[k].Tags, l2.Items[k].Tags)
+				}
+				if l1.Items[k].Parent != l2.Items[k].Parent || len(l1.Items[k].BlockedBy) != len(l2.Items[k].BlockedBy) {
+					t.Errorf("Expected parent %d and blockers %v, got %d and %v.",
+						l1.Items[k].Parent, l1.Items[k].BlockedBy, l2.Items[k].Parent, l2.Items[k].BlockedBy)
+				}
+				if (l1.Items[k].Repeat == nil) != (l2.Items[k].Repeat == nil) ||
+					l1.Items[k].Repeat != nil && l1.Items[k].Repeat.String() != l2.Items[k].Repeat.String() {
+					t.Errorf("Expected recurrence %v, got %v.", l1.Items[k].Repeat, l2.Items[k].Repeat)
+				}
+				if l1.Items[k].Notes != l2.Items[k].Notes || fmt.Sprint(l1.Items[k].Attachments) != fmt.Sprint(l2.Items[k].Attachments) {
+					t.Errorf("Expected notes %q and attachments %v, got %q and %v.",
+						l1.Items[k].Notes, l1.Items[k].Attachments, l2.Items[k].Notes, l2.Items[k].Attachments)
+				}
+				if !l1.Items[k].UpdatedAt.Equal(l2.Items[k].UpdatedAt) {
+					t.Errorf("Expected updated at %s, got %s.", l1.Items[k].UpdatedAt, l2.Items[k].UpdatedAt)
+				}
+				if !l1.Items[k].CreatedAt.Equal(l2.Items[k].CreatedAt) {
+					t.Errorf("Expected created at %s, got %s.", l1.Items[k].CreatedAt, l2.Items[k].CreatedAt)
+				}
+			}
+			if !l2.Items[1].CompletedAt.Equal(l1.Items[1].CompletedAt) {
+				t.Errorf("Expected completed at %s, got %s.", l1.Items[1].CompletedAt, l2.Items[1].CompletedAt)
+			}
+		})
+	}
+}
+
+// Tests that every backend keeps the IDs of removed items from being given again
+func TestNextIDSaved(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, uri := range []string{
+		filepath.Join(dir, "todo.json"),
+		"sqlite://" + filepath.Join(dir, "todo.db"),
+		"memory://",
+	} {
+		t.Run(uri, func(t *testing.T) {
+			store, err := storage.New(uri)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = todo.Update(store, func(l *todo.List) error {
+				l.Add("Task 1")
+				l.Add("Task 2")
+				return l.Delete(2)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			l := todo.List{}
+			if err := store.Load(&l); err != nil {
+				t.Fatal(err)
+			}
+			l.Add("Task 3")
+			if l.Items[1].ID != 3 {
+				t.Errorf("Expected ID 3 for the new item, got %d.", l.Items[1].ID)
+			}
+		})
+	}
+}
+
+// Tests that the in-memory store does not share items with the caller
+func TestInMemoryCopiesList(t *testing.T) {
+	store := storage.NewInMemory()
+
+	l1 := todo.List{}
+	l1.Add("Task 1", todo.WithTags("ops"))
+	store.Save(&l1)
+	l1.Items[0].Task = "Changed"
+	l1.Items[0].Tags[0] = "changed"
+
+	l2 := todo.List{}
+	store.Load(&l2)
+	if l2.Items[0].Task != "Task 1" || l2.Items[0].Tags[0] != "ops" {
+		t.Errorf("Expected stored item to be unchanged, got %v.", l2.Items[0])
+	}
+}
+
+func TestNewUnknownScheme(t *testing.T) {
+	_, err := storage.New("redis://localhost")
+	if !errors.Is(err, storage.ErrUnknownScheme) {
+		t.Errorf("Expected error %q, got %q.", storage.ErrUnknownScheme, err)
+	}
+}
+
+// Tests that the history is kept by every storage backend
+func TestUpdateUndo(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, uri := range []string{
+		filepath.Join(dir, "todo.json"),
+		"sqlite://" + filepath.Join(dir, "todo.db"),
+		"memory://",
+	} {
+		t.Run(uri, func(t *testing.T) {
+			store, err := storage.New(uri)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			add := func(l *todo.List) error { l.Add("Task"); return nil }
+			if err := todo.Update(store, add); err != nil {
+				t.Fatal(err)
+			}
+			if err := todo.Update(store, add); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := todo.Undo(store); err != nil {
+				t.Fatalf("Expected no error, got %q.", err)
+			}
+
+			l := todo.List{}
+			store.Load(&l)
+			if len(l.Items) != 1 {
+				t.Errorf("Expected 1 item after undo, got %d.", len(l.Items))
+			}
+
+			if err := todo.Redo(store); err != nil {
+				t.Fatalf("Expected no error, got %q.", err)
+			}
+			store.Load(&l)
+			if len(l.Items) != 2 {
+				t.Errorf("Expected 2 items after redo, got %d.", len(l.Items))
+			}
+		})
+	}
+}
+
+// Tests named lists and moving items between them with every storage backend
+func TestProjects(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, uri := range []string{
+		filepath.Join(dir, "todo.json"),
+		"sqlite://" + filepath.Join(dir, "todo.db"),
+		"memory://",
+	} {
+		t.Run(uri, func(t *testing.T) {
+			// Arrange
+			store, err := storage.New(uri)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			work, err := store.Project("work")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			add := func(task string) func(l *todo.List) error {
+				return func(l *todo.List) error { l.Add(task); return nil }
+			}
+			todo.Update(store, add("Home task"))
+			todo.Update(work, add("Work task 1"))
+			todo.Update(work, add("Work task 2"))
+
+			// Act
+			id, err := todo.MoveToProject(store, 1, "work", todo.DefaultProject)
+			if err != nil {
+				t.Fatalf("Expected no error, got %q.", err)
+			}
+
+			// Assert
+			if id != 2 {
+				t.Errorf("Expected new ID 2, got %d.", id)
+			}
+
+			names, err := store.Projects()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(names) != 2 || names[0] != todo.DefaultProject || names[1] != "work" {
+				t.Errorf("Expected projects [default work], got %v.", names)
+			}
+
+			l := todo.List{}
+			store.Load(&l)
+			if len(l.Items) != 2 || l.Items[1].Task != "Work task 1" {
+				t.Errorf("Expected moved task in the default project, got %v.", l)
+			}
+
+			work.Load(&l)
+			if len(l.Items) != 1 || l.Items[0].Task != "Work task 2" {
+				t.Errorf("Expected one task left in the work project, got %v.", l)
+			}
+
+			// The move is recorded in the history of both projects
+			if err := todo.Undo(work); err != nil {
+				t.Fatal(err)
+			}
+			work.Load(&l)
+			if len(l.Items) != 2 {
+				t.Errorf("Expected 2 items after undo, got %d.", len(l.Items))
+			}
+		})
+	}
+}
+
+func TestInvalidProject(t *testing.T) {
+	_, err := storage.NewInMemory().Project("a/b")
+	if !errors.Is(err, todo.ErrInvalidProject) {
+		t.Errorf("Expected error %q, got %q.", todo.ErrInvalidProject, err)
+	}
+}
+
+// Tests that items of a database created before projects go to the default project
+func TestSQLiteMigrateProjects(t *testing.T) {
+	// Arrange
+	dbfile := filepath.Join(t.TempDir(), "todo.db")
+	db, err := sql.Open("sqlite3", dbfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, stmt := range []string{
+		`CREATE TABLE "item" ("id" INTEGER, "position" INTEGER NOT NULL, "task" TEXT NOT NULL,
+			"done" BOOLEAN DEFAULT 0, "created_at" DATETIME NOT NULL, "completed_at" DATETIME,
+			"priority" TEXT DEFAULT '', "due" DATETIME, "tags" TEXT DEFAULT '', PRIMARY KEY ("id"))`,
+		`CREATE TABLE "history" ("id" INTEGER CHECK ("id" = 1), "data" TEXT NOT NULL, PRIMARY KEY ("id"))`,
+		`INSERT INTO item (id, position, task, created_at, completed_at, due, tags)
+			VALUES (1, 0, 'Old task', CURRENT_TIMESTAMP, '0001-01-01 00:00:00+00:00', '0001-01-01 00:00:00+00:00', 'ops,infra')`,
+		`INSERT INTO history VALUES (1, '{"Entries":[],"Current":0}')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	// Act
+	store, err := storage.NewSQLite3(dbfile)
+	if err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	// Assert
+	l := todo.List{}
+	if err := store.Load(&l); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Items) != 1 || l.Items[0].Task != "Old task" {
+		t.Fatalf("Expected the old task in the default project, got %v.", l)
+	}
+	if fmt.Sprint(l.Items[0].Tags) != "[ops infra]" {
+		t.Errorf("Expected the comma separated tags, got %q.", l.Items[0].Tags)
+	}
+
+	h := todo.History{}
+	if err := store.LoadHistory(&h); err != nil {
+		t.Errorf("Expected no error loading history, got %q.", err)
+	}
+}
+
+// Tests archiving, restoring and purging with every storage backend
+func TestArchiveRestorePurge(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, uri := range []string{
+		filepath.Join(dir, "todo.json"),
+		"sqlite://" + filepath.Join(dir, "todo.db"),
+		"memory://",
+	} {
+		t.Run(uri, func(t *testing.T) {
+			// Arrange
+			store, err := storage.New(uri)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			todo.Update(store, func(l *todo.List) error {
+				l.Add("Task 1")
+				l.Add("Task 2")
+				l.Add("Task 3")
+				l.Complete(1)
+				l.Complete(3)
+				return nil
+			})
+
+			archive, err := store.(todo.ArchiveStorage).Archive()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Act
+			n, err := todo.Archive(store, 0)
+			if err != nil {
+				t.Fatalf("Expected no error, got %q.", err)
+			}
+
+			// Assert
+			if n != 2 {
+				t.Errorf("Expected 2 archived items, got %d.", n)
+			}
+
+			l := todo.List{}
+			store.Load(&l)
+			if len(l.Items) != 1 || l.Items[0].Task != "Task 2" {
+				t.Errorf("Expected only the open task in the list, got %v.", l)
+			}
+
+			archive.Load(&l)
+			if len(l.Items) != 2 || l.Items[0].ID != 1 || l.Items[1].ID != 2 || l.Items[1].Task != "Task 3" {
+				t.Errorf("Expected 2 renumbered items in the archive, got %v.", l)
+			}
+
+			id, err := todo.Restore(store, 2)
+			if err != nil {
+				t.Fatalf("Expected no error restoring, got %q.", err)
+			}
+			// ID 3 left with the archived item and is not given again
+			if id != 4 {
+				t.Errorf("Expected restored item to get ID 4, got %d.", id)
+			}
+
+			store.Load(&l)
+			if len(l.Items) != 2 || l.Items[1].Task != "Task 3" {
+				t.Errorf("Expected restored task at the end of the list, got %v.", l)
+			}
+
+			n, err = todo.Purge(store)
+			if err != nil {
+				t.Fatalf("Expected no error purging, got %q.", err)
+			}
+			if n != 1 {
+				t.Errorf("Expected 1 purged item, got %d.", n)
+			}
+
+			archive.Load(&l)
+			if len(l.Items) != 0 {
+				t.Errorf("Expected empty archive, got %v.", l)
+			}
+		})
+	}
+}
+
+func TestSyncState(t *testing.T) {
+	dir := t.TempDir()
+	synced := time.Date(2026, time.October, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, uri := range []string{
+		filepath.Join(dir, "todo.json"),
+		"sqlite://" + filepath.Join(dir, "todo.db"),
+		"memory://",
+	} {
+		t.Run(uri, func(t *testing.T) {
+			// Arrange
+			store, err := storage.New(uri)
+			if err != nil {
+				t.Fatal(err)
+			}
+			work, err := store.Project("work")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			state := todo.SyncState{Remotes: map[string]time.Time{"http://host:8080/todo": synced}}
+
+			// Act
+			if err := store.(todo.SyncStorage).SaveSyncState(&state); err != nil {
+				t.Fatalf("Expected no error, got %q.", err)
+			}
+
+			// Assert
+			loaded := todo.SyncState{}
+			if err := store.(todo.SyncStorage).LoadSyncState(&loaded); err != nil {
+				t.Fatal(err)
+			}
+			if got := loaded.Remotes["http://host:8080/todo"]; !got.Equal(synced) {
+				t.Errorf("Expected %s, got %s.", synced, got)
+			}
+
+			// Projects are synced on their own
+			other := todo.SyncState{}
+			if err := work.(todo.SyncStorage).LoadSyncState(&other); err != nil {
+				t.Fatal(err)
+			}
+			if len(other.Remotes) != 0 {
+				t.Errorf("Expected no sync state for project work, got %v.", other.Remotes)
+			}
+		})
+	}
+}
+
+// Tests that the list, the history and the archive of an encrypted JSON file are encrypted
+func TestEncryptedJSONFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "todo.json")
+
+	c, err := todo.NewCipher([]byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := storage.New(filename, storage.WithCipher(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := todo.Update(store, func(l *todo.List) error {
+		l.Add("Task 1")
+		return l.Complete(1)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := todo.Archive(store, -time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range []string{filename, filename + ".history", filename + ".archive"} {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !todo.IsEncrypted(data) {
+			t.Errorf("Expected %s to be encrypted, got %q.", f, data)
+		}
+	}
+
+	plain, err := storage.New(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plain.Load(&todo.List{}); !errors.Is(err, todo.ErrEncrypted) {
+		t.Errorf("Expected %q, got %q.", todo.ErrEncrypted, err)
+	}
+
+	_, err = storage.New("sqlite://"+filepath.Join(dir, "todo.db"), storage.WithCipher(c))
+	if !errors.Is(err, storage.ErrNoEncryption) {
+		t.Errorf("Expected %q, got %q.", storage.ErrNoEncryption, err)
+	}
+}
+
+// Tests that plain files are only opened with a key after they are encrypted
+func TestEncryptPlainJSONFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+
+	plain, err := storage.New(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	work, err := plain.Project("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []todo.Storage{plain, work} {
+		if err := todo.Update(s, func(l *todo.List) error {
+			l.Add("Task 1")
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := todo.NewCipher([]byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := storage.New(filename, storage.WithCipher(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Load(&todo.List{}); !errors.Is(err, todo.ErrNotEncrypted) {
+		t.Fatalf("Expected %q, got %q.", todo.ErrNotEncrypted, err)
+	}
+
+	// The lists and the histories of both projects
+	n, err := storage.Encrypt(filename, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 encrypted files, got %d.", n)
+	}
+
+	for _, f := range []string{filename, filename + ".history", filename + ".work.history"} {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !todo.IsEncrypted(data) {
+			t.Errorf("Expected %s to be encrypted, got %q.", f, data)
+		}
+	}
+
+	l := todo.List{}
+	if err := store.Load(&l); err != nil || len(l.Items) != 1 {
+		t.Errorf("Expected the encrypted list, got %v, %v.", l, err)
+	}
+
+	if n, err := storage.Encrypt(filename, c); err != nil || n != 0 {
+		t.Errorf("Expected nothing to encrypt again, got %d, %v.", n, err)
+	}
+	if _, err := storage.Encrypt("memory://", c); !errors.Is(err, storage.ErrNoEncryption) {
+		t.Errorf("Expected %q, got %q.", storage.ErrNoEncryption, err)
+	}
+}
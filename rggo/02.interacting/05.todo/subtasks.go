@@ -0,0 +1,234 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Returned when completing an item with open subtasks without force
+	ErrOpenSubtasks = errors.New("open subtasks")
+	// Returned when the parent would make the item its own ancestor
+	ErrInvalidParent = errors.New("invalid parent")
+	// Returned when an item would block itself
+	ErrInvalidBlocker = errors.New("invalid blocker")
+)
+
+// Makes the item with the given ID a subtask of the parent. Parent 0 makes it a top level item.
+func (l *List) SetParent(id, parent int) error {
+	idx, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+
+	// Walk up from the new parent to make sure the item is not its ancestor
+	for p := parent; p != 0; {
+		if p == id {
+			return fmt.Errorf("%w: item %d cannot be a subtask of %d", ErrInvalidParent, id, parent)
+		}
+
+		pIdx, err := l.Index(p)
+		if err != nil {
+			return err
+		}
+		p = l.Items[pIdx].Parent
+	}
+
+	l.Items[idx].Parent = parent
+	l.Items[idx].touch()
+	return nil
+}
+
+// Returns an error if an item is its own ancestor, which SetParent prevents
+// but lists made by other means may have
+func (l *List) checkParents() error {
+	parents := make(map[int]int, len(l.Items))
+	for _, t := range l.Items {
+		parents[t.ID] = t.Parent
+	}
+
+	for _, t := range l.Items {
+		seen := map[int]bool{}
+		for p := t.Parent; p != 0 && !seen[p]; p = parents[p] {
+			if p == t.ID {
+				return fmt.Errorf("%w: item %d is its own ancestor", ErrInvalidParent, t.ID)
+			}
+			seen[p] = true
+		}
+	}
+
+	return nil
+}
+
+// Marks the item with the given ID as blocked by the other item
+func (l *List) Block(id, blocker int) error {
+	idx, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+
+	if id == blocker {
+		return fmt.Errorf("%w: item %d cannot block itself", ErrInvalidBlocker, id)
+	}
+
+	if _, err := l.Index(blocker); err != nil {
+		return err
+	}
+
+	t := &l.Items[idx]
+	for _, b := range t.BlockedBy {
+		if b == blocker {
+			return nil
+		}
+	}
+	t.BlockedBy = append(t.BlockedBy, blocker)
+	t.touch()
+
+	return nil
+}
+
+// Removes the other item from the items blocking the item with the given ID
+func (l *List) Unblock(id, blocker int) error {
+	idx, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+
+	t := &l.Items[idx]
+	n := len(t.BlockedBy)
+	t.BlockedBy = removeID(t.BlockedBy, blocker)
+	if len(t.BlockedBy) != n {
+		t.touch()
+	}
+	return nil
+}
+
+// Returns the IDs of the open items blocking the item
+func (l *List) blockers(t *item) []int {
+	open := []int{}
+	for _, b := range t.BlockedBy {
+		if idx, err := l.Index(b); err == nil && !l.Items[idx].Done {
+			open = append(open, b)
+		}
+	}
+
+	return open
+}
+
+// Returns the IDs of the open subtasks of the item with the given ID
+func (l *List) openChildren(id int) []int {
+	open := []int{}
+	for _, t := range l.Items {
+		if t.Parent == id && !t.Done {
+			open = append(open, t.ID)
+		}
+	}
+
+	return open
+}
+
+// Removes references to a deleted item. Its subtasks move to its parent.
+func (l *List) detach(id, parent int) {
+	ls := l.Items
+	for k := range ls {
+		changed := false
+		if ls[k].Parent == id {
+			ls[k].Parent = parent
+			changed = true
+		}
+		n := len(ls[k].BlockedBy)
+		ls[k].BlockedBy = removeID(ls[k].BlockedBy, id)
+		if len(ls[k].BlockedBy) != n {
+			changed = true
+		}
+		if changed {
+			ls[k].touch()
+		}
+	}
+}
+
+// Item with its level in the tree of subtasks
+type node struct {
+	t     item
+	depth int
+}
+
+// Returns the items in depth-first order with subtasks after their parents.
+// Items whose parent is not in the list are shown at the top level,
+// like the items of a parent cycle, so every item is returned once.
+func (l *List) tree() []node {
+	ids := make(map[int]bool, len(l.Items))
+	for _, t := range l.Items {
+		ids[t.ID] = true
+	}
+
+	children := map[int][]item{}
+	roots := []item{}
+	for _, t := range l.Items {
+		if ids[t.Parent] {
+			children[t.Parent] = append(children[t.Parent], t)
+			continue
+		}
+		roots = append(roots, t)
+	}
+
+	nodes := make([]node, 0, len(l.Items))
+	walked := make(map[int]bool, len(l.Items))
+	var walk func(t item, depth int)
+	walk = func(t item, depth int) {
+		walked[t.ID] = true
+		nodes = append(nodes, node{t: t, depth: depth})
+		for _, c := range children[t.ID] {
+			if !walked[c.ID] {
+				walk(c, depth+1)
+			}
+		}
+	}
+
+	for _, t := range roots {
+		walk(t, 0)
+	}
+	for _, t := range l.Items {
+		if !walked[t.ID] {
+			walk(t, 0)
+		}
+	}
+
+	return nodes
+}
+
+// Formats the open blockers to append after the task name
+func (l *List) blockersAsString(t *item) string {
+	open := l.blockers(t)
+	if len(open) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" [blocked by %s]", joinIDs(open))
+}
+
+func joinIDs(ids []int) string {
+	s := make([]string, len(ids))
+	for k, id := range ids {
+		s[k] = strconv.Itoa(id)
+	}
+
+	return strings.Join(s, ", ")
+}
+
+func removeID(ids []int, id int) []int {
+	kept := ids[:0]
+	for _, i := range ids {
+		if i != id {
+			kept = append(kept, i)
+		}
+	}
+
+	if len(kept) == 0 {
+		return nil
+	}
+
+	return kept
+}
@@ -0,0 +1,121 @@
+package todo_test
+
+import (
+	"errors"
+	"rggo/interacting/todo"
+	"testing"
+)
+
+// Tests that a parent with open subtasks is completed only with force
+func TestCompleteParent(t *testing.T) {
+	// Arrange
+	l := todo.List{}
+	l.Add("Parent")
+	l.Add("Subtask")
+	if err := l.SetParent(2, 1); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	// Act, Assert
+	if err := l.Complete(1); !errors.Is(err, todo.ErrOpenSubtasks) {
+		t.Fatalf("Expected error %q, got %q.", todo.ErrOpenSubtasks, err)
+	}
+
+	if err := l.ForceComplete(1); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+	if !l.Items[0].Done {
+		t.Errorf("Expected parent to be completed.")
+	}
+
+	l.Reopen(1)
+	l.Complete(2)
+	if err := l.Complete(1); err != nil {
+		t.Errorf("Expected no error once subtasks are completed, got %q.", err)
+	}
+}
+
+// Tests that an item cannot become its own ancestor
+func TestSetParentCycle(t *testing.T) {
+	l := todo.List{}
+	l.Add("Task 1")
+	l.Add("Task 2")
+	l.SetParent(2, 1)
+
+	if err := l.SetParent(1, 2); !errors.Is(err, todo.ErrInvalidParent) {
+		t.Errorf("Expected error %q, got %q.", todo.ErrInvalidParent, err)
+	}
+	if err := l.SetParent(1, 3); !errors.Is(err, todo.ErrItemNotFound) {
+		t.Errorf("Expected error %q, got %q.", todo.ErrItemNotFound, err)
+	}
+}
+
+// Tests that deleting an item keeps its subtasks and removes it from the blockers
+func TestDeleteDetaches(t *testing.T) {
+	// Arrange
+	l := todo.List{}
+	l.Add("Task 1")
+	l.Add("Task 2")
+	l.Add("Task 3")
+	l.SetParent(2, 1)
+	l.SetParent(3, 2)
+	l.Block(1, 3)
+
+	// Act
+	l.Delete(2)
+
+	// Assert
+	if l.Items[1].Parent != 1 {
+		t.Errorf("Expected parent %d, got %d.", 1, l.Items[1].Parent)
+	}
+
+	l.Delete(3)
+	if len(l.Items[0].BlockedBy) != 0 {
+		t.Errorf("Expected no blockers, got %v.", l.Items[0].BlockedBy)
+	}
+}
+
+// Tests printing of subtasks and blocked items
+func TestPrintTree(t *testing.T) {
+	// Arrange
+	l := todo.List{}
+	l.Add("Release")
+	l.Add("Write notes")
+	l.Add("Tag version")
+	l.Add("Build")
+	l.SetParent(2, 1)
+	l.SetParent(3, 1)
+	l.Block(3, 2)
+	l.Block(1, 4)
+	l.Complete(4)
+
+	expected :=
+		"  1: Release        \n" +
+			"    2: Write notes        \n" +
+			"    3: Tag version [blocked by 2]        \n" +
+			"X 4: Build        \n"
+
+	// Act
+	actual := l.Print(false, false)
+
+	// Assert
+	assertString(expected, actual, t)
+
+	if err := l.Block(1, 1); !errors.Is(err, todo.ErrInvalidBlocker) {
+		t.Errorf("Expected error %q, got %q.", todo.ErrInvalidBlocker, err)
+	}
+}
+
+// Tests that the items of a parent cycle, which lists made by hand may have, are still printed
+func TestPrintTreeCycle(t *testing.T) {
+	l := todo.List{}
+	l.Add("Task A")
+	l.Add("Task B")
+	l.Items[0].Parent = 2
+	l.Items[1].Parent = 1
+
+	expected :=
+		"  1: Task A        \n" +
+			"    2: Task B        \n"
+	assertString(expected, l.Print(false, false), t)
+}
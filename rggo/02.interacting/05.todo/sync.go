@@ -0,0 +1,335 @@
+package todo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var (
+	// Returned when a list received from elsewhere cannot be stored
+	ErrInvalidList = errors.New("invalid list")
+	// Returned by a remote storage saving a list that changed since it was loaded
+	ErrRemoteChanged = errors.New("remote list changed")
+)
+
+// Number of times Sync reconciles with a remote list that keeps changing
+const syncAttempts = 3
+
+// Reasons of the sync conflicts
+const (
+	ConflictChanged        = "changed on both sides"
+	ConflictDeletedLocally = "deleted locally but changed remotely"
+	ConflictDeletedRemote  = "deleted remotely but changed locally"
+)
+
+// Time of the last sync with each remote list
+type SyncState struct {
+	Remotes map[string]time.Time
+}
+
+// Saves the state as JSON in the file
+func (s *SyncState) Save(filename string) error {
+	return s.SaveWith(filename, nil)
+}
+
+// Saves the state like Save, encrypted with the cipher unless it is nil
+func (s *SyncState) SaveWith(filename string, c *Cipher) error {
+	js, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteFile(filename, js)
+}
+
+// Opens the provided file name and decodes the JSON data into the state
+func (s *SyncState) Get(filename string) error {
+	return s.GetWith(filename, nil)
+}
+
+// Gets the state like Get, decrypting the file with the cipher if it is encrypted
+func (s *SyncState) GetWith(filename string, c *Cipher) error {
+	file, err := c.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	if len(file) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(file, s)
+}
+
+// Storage that remembers when the list was synced with remote lists
+type SyncStorage interface {
+	LoadSyncState(s *SyncState) error
+	SaveSyncState(s *SyncState) error
+}
+
+// Item changed on both sides since the last sync. Both versions are kept.
+type Conflict struct {
+	ID     int
+	Task   string
+	Reason string
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%d: %s %s", c.ID, c.Task, c.Reason)
+}
+
+// Outcome of a sync
+type SyncResult struct {
+	// Remote changes applied to the local list
+	Pulled int
+	// Local changes applied to the remote list
+	Pushed    int
+	Conflicts []Conflict
+}
+
+// Checks that the IDs are positive and unique, that every item has a task
+// and that no item is its own ancestor
+func (l *List) Validate() error {
+	ids := make(map[int]bool, len(l.Items))
+	for _, t := range l.Items {
+		switch {
+		case t.ID < 1:
+			return fmt.Errorf("%w: ID %d is less than one", ErrInvalidList, t.ID)
+		case ids[t.ID]:
+			return fmt.Errorf("%w: ID %d is repeated", ErrInvalidList, t.ID)
+		case t.Task == "":
+			return fmt.Errorf("%w: item %d has no task", ErrInvalidList, t.ID)
+		}
+		ids[t.ID] = true
+	}
+
+	if err := l.checkParents(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidList, err)
+	}
+
+	return nil
+}
+
+// Reconciles the list of the storage with the remote list named name,
+// for instance its URL, and saves the result in both.
+// The storage is locked for the whole sync if it supports locking.
+// If the remote list changes meanwhile, saving it fails with ErrRemoteChanged
+// and it is loaded and reconciled again.
+// Without a SyncStorage every item is new to the sync, so deletions are not applied.
+func Sync(s, remote Storage, name string) (SyncResult, error) {
+	if lk, ok := s.(Locker); ok {
+		if err := lk.Lock(); err != nil {
+			return SyncResult{}, err
+		}
+		defer lk.Unlock()
+	}
+
+	state := SyncState{}
+	ss, hasState := s.(SyncStorage)
+	if hasState {
+		if err := ss.LoadSyncState(&state); err != nil {
+			return SyncResult{}, err
+		}
+	}
+
+	var (
+		started time.Time
+		result  SyncResult
+		err     error
+	)
+	for attempt := 0; attempt < syncAttempts; attempt++ {
+		// Changes made while syncing are picked by the next sync
+		started = time.Now()
+		result, err = syncOnce(s, remote, state.Remotes[name])
+		if !errors.Is(err, ErrRemoteChanged) {
+			break
+		}
+	}
+	if err != nil || !hasState {
+		return result, err
+	}
+
+	if state.Remotes == nil {
+		state.Remotes = map[string]time.Time{}
+	}
+	state.Remotes[name] = started
+
+	return result, ss.SaveSyncState(&state)
+}
+
+// Reconciles the list of the storage once with the remote list.
+// Nothing is saved locally if the remote list cannot be saved.
+func syncOnce(s, remote Storage, since time.Time) (SyncResult, error) {
+	rl := List{}
+	if err := remote.Load(&rl); err != nil {
+		return SyncResult{}, err
+	}
+
+	result := SyncResult{}
+	err := modify(s, func(l *List, h *History) error {
+		before := l.Clone()
+
+		var newRemote List
+		*l, newRemote, result = Reconcile(*l, rl, since)
+		// Reconcile sets NextID on both sides, which alone is no change to send
+		if !reflect.DeepEqual(newRemote.Items, rl.Items) {
+			if err := remote.Save(&newRemote); err != nil {
+				return err
+			}
+		}
+
+		h.Record(before, *l)
+		return nil
+	})
+
+	return result, err
+}
+
+// Merges the changes made to the local and the remote lists since the last sync
+// and returns the new local and remote lists.
+// Items with the same ID and creation time are the same item on both sides.
+// A change made on one side only is copied to the other side. Changes made
+// on both sides, and changes of items deleted on the other side, are conflicts:
+// both versions are kept and the conflict is reported.
+// New local items whose IDs are taken by other remote items get new IDs.
+func Reconcile(local, remote List, since time.Time) (List, List, SyncResult) {
+	local, remote = local.Clone(), remote.Clone()
+	result := SyncResult{}
+
+	remoteIdx := make(map[int]int, len(remote.Items))
+	for k, t := range remote.Items {
+		remoteIdx[t.ID] = k
+	}
+
+	// IDs given on either side are not given again on both
+	next := local.nextID()
+	if n := remote.nextID(); n > next {
+		next = n
+	}
+	for k := range local.Items {
+		lt := &local.Items[k]
+		if rk, ok := remoteIdx[lt.ID]; ok && !lt.CreatedAt.Equal(remote.Items[rk].CreatedAt) {
+			local.renumber(lt.ID, next)
+			next++
+		}
+	}
+	local.NextID, remote.NextID = next, next
+
+	localIdx := make(map[int]int, len(local.Items))
+	for k, t := range local.Items {
+		localIdx[t.ID] = k
+	}
+
+	changed := func(t *item) bool { return t.modified().After(since) }
+	isNew := func(t *item) bool { return t.CreatedAt.After(since) }
+
+	deletedLocally, deletedRemotely := []int{}, []int{}
+	for k := range local.Items {
+		lt := &local.Items[k]
+		rk, ok := remoteIdx[lt.ID]
+		if !ok {
+			switch {
+			case isNew(lt):
+				remote.Items = append(remote.Items, lt.clone())
+				result.Pushed++
+			case changed(lt):
+				remote.Items = append(remote.Items, lt.clone())
+				result.Conflicts = append(result.Conflicts, Conflict{ID: lt.ID, Task: lt.Task, Reason: ConflictDeletedRemote})
+			default:
+				deletedRemotely = append(deletedRemotely, lt.ID)
+			}
+			continue
+		}
+
+		rt := &remote.Items[rk]
+		if lt.sameContent(rt) {
+			continue
+		}
+
+		switch lc, rc := changed(lt), changed(rt); {
+		case lc && !rc:
+			*rt = lt.clone()
+			result.Pushed++
+		case rc && !lc:
+			// Reminders are sent locally, until the due date changes
+			if lt.Due.Equal(rt.Due) {
+				reminded := lt.Reminded
+				*lt = rt.clone()
+				lt.Reminded = reminded
+			} else {
+				*lt = rt.clone()
+				lt.Reminded = UrgencyNone
+			}
+			result.Pulled++
+		default:
+			result.Conflicts = append(result.Conflicts, Conflict{ID: lt.ID, Task: lt.Task, Reason: ConflictChanged})
+		}
+	}
+
+	for k := range remote.Items {
+		rt := &remote.Items[k]
+		if _, ok := localIdx[rt.ID]; ok {
+			continue
+		}
+
+		switch {
+		case isNew(rt):
+			local.Items = append(local.Items, rt.clone())
+			result.Pulled++
+		case changed(rt):
+			local.Items = append(local.Items, rt.clone())
+			result.Conflicts = append(result.Conflicts, Conflict{ID: rt.ID, Task: rt.Task, Reason: ConflictDeletedLocally})
+		default:
+			deletedLocally = append(deletedLocally, rt.ID)
+		}
+	}
+
+	for _, id := range deletedRemotely {
+		local.Delete(id)
+		result.Pulled++
+	}
+	for _, id := range deletedLocally {
+		remote.Delete(id)
+		result.Pushed++
+	}
+
+	return local, remote, result
+}
+
+// Changes the ID of the item and the references to it.
+// The old ID is not given again.
+func (l *List) renumber(id, newID int) {
+	l.reserve(id)
+	for k := range l.Items {
+		t := &l.Items[k]
+		if t.ID == id {
+			t.ID = newID
+		}
+		if t.Parent == id {
+			t.Parent = newID
+		}
+		for b := range t.BlockedBy {
+			if t.BlockedBy[b] == id {
+				t.BlockedBy[b] = newID
+			}
+		}
+	}
+}
+
+// Reports whether both items have the same content. The time of the last change
+// and the sent reminders are not content, and times are compared as instants.
+func (t *item) sameContent(other *item) bool {
+	normalize := func(t item) item {
+		t.Reminded = UrgencyNone
+		t.UpdatedAt = time.Time{}
+		t.CreatedAt = t.CreatedAt.Round(0).UTC()
+		t.CompletedAt = t.CompletedAt.Round(0).UTC()
+		t.Due = t.Due.Round(0).UTC()
+		return t
+	}
+
+	return reflect.DeepEqual(normalize(t.clone()), normalize(other.clone()))
+}
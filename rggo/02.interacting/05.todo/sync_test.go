@@ -0,0 +1,181 @@
+package todo_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"rggo/interacting/todo"
+)
+
+// Tests merging the changes made on both sides since the last sync
+func TestReconcile(t *testing.T) {
+	base := todo.List{}
+	base.Add("Task 1")
+	base.Add("Task 2")
+
+	testCases := []struct {
+		name      string
+		local     func(l *todo.List)
+		remote    func(l *todo.List)
+		expLocal  []string
+		expRemote []string
+		expResult todo.SyncResult
+	}{
+		{
+			name:      "NoChanges",
+			local:     func(l *todo.List) {},
+			remote:    func(l *todo.List) {},
+			expLocal:  []string{"1: Task 1", "2: Task 2"},
+			expRemote: []string{"1: Task 1", "2: Task 2"},
+		},
+		{
+			name:      "RemoteEdit",
+			local:     func(l *todo.List) {},
+			remote:    func(l *todo.List) { l.Edit(1, "Remote 1") },
+			expLocal:  []string{"1: Remote 1", "2: Task 2"},
+			expRemote: []string{"1: Remote 1", "2: Task 2"},
+			expResult: todo.SyncResult{Pulled: 1},
+		},
+		{
+			name:      "LocalAddRemoteDelete",
+			local:     func(l *todo.List) { l.Add("Local 3") },
+			remote:    func(l *todo.List) { l.Delete(2) },
+			expLocal:  []string{"1: Task 1", "3: Local 3"},
+			expRemote: []string{"1: Task 1", "3: Local 3"},
+			expResult: todo.SyncResult{Pulled: 1, Pushed: 1},
+		},
+		{
+			name:      "BothEdit",
+			local:     func(l *todo.List) { l.Edit(2, "Local 2") },
+			remote:    func(l *todo.List) { l.Edit(2, "Remote 2") },
+			expLocal:  []string{"1: Task 1", "2: Local 2"},
+			expRemote: []string{"1: Task 1", "2: Remote 2"},
+			expResult: todo.SyncResult{Conflicts: []todo.Conflict{
+				{ID: 2, Task: "Local 2", Reason: todo.ConflictChanged},
+			}},
+		},
+		{
+			name:      "SameEdit",
+			local:     func(l *todo.List) { l.Complete(1) },
+			remote:    func(l *todo.List) { l.Complete(1) },
+			expLocal:  []string{"1: Task 1", "2: Task 2"},
+			expRemote: []string{"1: Task 1", "2: Task 2"},
+			expResult: todo.SyncResult{Conflicts: []todo.Conflict{
+				{ID: 1, Task: "Task 1", Reason: todo.ConflictChanged},
+			}},
+		},
+		{
+			name:      "EditDeleted",
+			local:     func(l *todo.List) { l.Edit(1, "Local 1") },
+			remote:    func(l *todo.List) { l.Delete(1) },
+			expLocal:  []string{"1: Local 1", "2: Task 2"},
+			expRemote: []string{"2: Task 2", "1: Local 1"},
+			expResult: todo.SyncResult{Conflicts: []todo.Conflict{
+				{ID: 1, Task: "Local 1", Reason: todo.ConflictDeletedRemote},
+			}},
+		},
+		{
+			name:      "SameNewID",
+			local:     func(l *todo.List) { l.Add("Local 3") },
+			remote:    func(l *todo.List) { l.Add("Remote 3") },
+			expLocal:  []string{"1: Task 1", "2: Task 2", "4: Local 3", "3: Remote 3"},
+			expRemote: []string{"1: Task 1", "2: Task 2", "3: Remote 3", "4: Local 3"},
+			expResult: todo.SyncResult{Pulled: 1, Pushed: 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			since := time.Now()
+			local, remote := base.Clone(), base.Clone()
+			tc.local(&local)
+			tc.remote(&remote)
+
+			// Act
+			newLocal, newRemote, result := todo.Reconcile(local, remote, since)
+
+			// Assert
+			if fmt.Sprint(result) != fmt.Sprint(tc.expResult) {
+				t.Errorf("Expected %+v, got %+v.", tc.expResult, result)
+			}
+			if got := tasks(newLocal); fmt.Sprint(got) != fmt.Sprint(tc.expLocal) {
+				t.Errorf("Expected local %q, got %q.", tc.expLocal, got)
+			}
+			if got := tasks(newRemote); fmt.Sprint(got) != fmt.Sprint(tc.expRemote) {
+				t.Errorf("Expected remote %q, got %q.", tc.expRemote, got)
+			}
+		})
+	}
+}
+
+// Tests that IDs removed on either side are not given again on both
+func TestReconcileKeepsNextID(t *testing.T) {
+	local := todo.List{}
+	local.Add("Task 1")
+	local.Add("Task 2")
+	remote := local.Clone()
+	local.Delete(2)
+
+	newLocal, newRemote, _ := todo.Reconcile(local, remote, time.Now())
+	newLocal.Add("Local 3")
+	newRemote.Add("Remote 3")
+
+	if got := tasks(newLocal); fmt.Sprint(got) != "[1: Task 1 3: Local 3]" {
+		t.Errorf("Expected the new local item to get ID 3, got %q.", got)
+	}
+	if got := tasks(newRemote); fmt.Sprint(got) != "[1: Task 1 3: Remote 3]" {
+		t.Errorf("Expected the new remote item to get ID 3, got %q.", got)
+	}
+}
+
+// Tests that the first sync copies the items missing on either side
+func TestReconcileFirstSync(t *testing.T) {
+	local, remote := todo.List{}, todo.List{}
+	local.Add("Local 1")
+
+	newLocal, newRemote, result := todo.Reconcile(local, remote, time.Time{})
+
+	if result.Pushed != 1 || result.Pulled != 0 {
+		t.Errorf("Expected 1 pushed item, got %+v.", result)
+	}
+	if len(newLocal.Items) != 1 || len(newRemote.Items) != 1 || newRemote.Items[0].Task != "Local 1" {
+		t.Errorf("Expected the local item on both sides, got %v and %v.", newLocal, newRemote)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	l := todo.List{}
+	l.Add("Task 1")
+	l.Add("Task 2")
+
+	if err := l.Validate(); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	l.Items[1].ID = 1
+	if err := l.Validate(); !errors.Is(err, todo.ErrInvalidList) {
+		t.Errorf("Expected %q, got %q.", todo.ErrInvalidList, err)
+	}
+
+	// Items that are their own ancestors
+	l.Items[1].ID = 2
+	l.Items[0].Parent = 2
+	l.Items[1].Parent = 1
+	err := l.Validate()
+	if !errors.Is(err, todo.ErrInvalidList) || !errors.Is(err, todo.ErrInvalidParent) {
+		t.Errorf("Expected %q, got %q.", todo.ErrInvalidParent, err)
+	}
+}
+
+// IDs and tasks of the list
+func tasks(l todo.List) []string {
+	s := []string{}
+	for _, t := range l.Items {
+		s = append(s, fmt.Sprintf("%d: %s", t.ID, t.Task))
+	}
+
+	return s
+}
@@ -1,94 +1,424 @@
 package todo
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
+var (
+	// Returned when there is no item with the requested ID in the list
+	ErrItemNotFound = errors.New("item not found")
+	// Returned when a position is outside of the list
+	ErrInvalidPosition = errors.New("invalid position")
+)
+
 // Represents a todo item
 type item struct {
+	ID          int
 	Task        string
 	Done        bool
 	CreatedAt   time.Time
 	CompletedAt time.Time
+	Priority    Priority
+	Due         time.Time
+	Tags        []string
+	Parent      int
+	BlockedBy   []int
+	Repeat      *Recurrence
+	// Urgency of the last reminder sent
+	Reminded Urgency `json:",omitempty"`
+	// Last change of the item, used to detect conflicts on sync
+	UpdatedAt   time.Time
+	Notes       string       `json:",omitempty"`
+	Attachments []Attachment `json:",omitempty"`
+	// Time spent focused on the item, loaded with LoadFocus and never saved
+	focused time.Duration
 }
 
 // Represents a list of todo items
-type List []item
+type List struct {
+	Items []item
+	// ID of the next new item when it is bigger than the IDs of the items.
+	// IDs of removed items are not given again, so the history, the archive
+	// and the sync state keep naming the same items.
+	NextID int
+}
 
-// Creates a new todo item and appends it to the list
-func (l *List) Add(task string) {
+// Creates a new todo item and appends it to the list.
+// Optional details like priority, due date or tags are set with options.
+func (l *List) Add(task string, opts ...Option) {
+	now := time.Now()
 	t := item{
+		ID:          l.nextID(),
 		Task:        task,
 		Done:        false,
-		CreatedAt:   time.Now(),
+		CreatedAt:   now,
 		CompletedAt: time.Time{},
+		UpdatedAt:   now,
+	}
+
+	for _, opt := range opts {
+		opt(&t)
 	}
 
-	*l = append(*l, t)
+	l.Items = append(l.Items, t)
 }
 
-// Marks a todo item as completed
-// by setting Done = true and CompletedAt to the current time
-func (l *List) Complete(i int) error {
-	ls := *l
-	if i <= 0 || i > len(ls) {
-		return fmt.Errorf("Item %d does not exists", i)
+// Marks a todo item with the given ID as completed
+// by setting Done = true and CompletedAt to the current time.
+// Items with open subtasks are not completed.
+func (l *List) Complete(id int) error {
+	if open := l.openChildren(id); len(open) > 0 {
+		return fmt.Errorf("%w: item %d has open subtasks %s", ErrOpenSubtasks, id, joinIDs(open))
 	}
 
-	idx := i - 1
+	return l.ForceComplete(id)
+}
+
+// Marks a todo item with the given ID as completed even if it has open subtasks.
+// Completing a recurring item adds its next occurrence to the list.
+func (l *List) ForceComplete(id int) error {
+	idx, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+
+	ls := l.Items
+	wasDone := ls[idx].Done
 	ls[idx].Done = true
 	ls[idx].CompletedAt = time.Now()
+	ls[idx].UpdatedAt = ls[idx].CompletedAt
+
+	if ls[idx].Repeat != nil && !wasDone {
+		l.spawnNext(&ls[idx])
+	}
 
 	return nil
 }
 
-// Deletes a todo item from the list
-func (l *List) Delete(i int) error {
-	ls := *l
-	if i <= 0 || i > len(ls) {
-		return fmt.Errorf("Item %d does not exists", i)
+// Deletes a todo item with the given ID from the list.
+// Its subtasks move to its parent and it no longer blocks other items.
+func (l *List) Delete(id int) error {
+	idx, err := l.Index(id)
+	if err != nil {
+		return err
 	}
 
-	*l = append(ls[:i-1], ls[i:]...)
+	l.reserve(id)
+	ls := l.Items
+	parent := ls[idx].Parent
+	l.Items = append(ls[:idx], ls[idx+1:]...)
+	l.detach(id, parent)
+
 	return nil
 }
 
-// Encodes the List as JSON and saves it using the provided file name
-func (l *List) Save(filename string) error {
-	js, err := json.Marshal(l)
+// Marks several items as completed. Subtasks in the batch are completed before their parents,
+// so a parent can be completed together with its open subtasks.
+// The list may be partly changed when an error is returned.
+func (l *List) CompleteMany(ids []int) error {
+	pending := ids
+	for len(pending) > 0 {
+		waiting := []int{}
+		var err error
+		for _, id := range pending {
+			err = l.Complete(id)
+			if errors.Is(err, ErrOpenSubtasks) {
+				waiting = append(waiting, id)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		// No subtask of the waiting items is in the batch
+		if len(waiting) == len(pending) {
+			return err
+		}
+		pending = waiting
+	}
+
+	return nil
+}
+
+// Deletes several items.
+// The list may be partly changed when an error is returned.
+func (l *List) DeleteMany(ids []int) error {
+	for _, id := range ids {
+		if err := l.Delete(id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Replaces the task name of the item with the given ID
+func (l *List) Edit(id int, newTask string) error {
+	idx, err := l.Index(id)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(filename, js, 0644)
+	l.Items[idx].Task = newTask
+	l.Items[idx].touch()
+	return nil
 }
 
-// Opens the provided file name, decodes the JSON data and parses it into a List
-func (l *List) Get(filename string) error {
-	file, err := os.ReadFile(filename)
+// Marks a completed todo item with the given ID as not completed
+func (l *List) Reopen(id int) error {
+	idx, err := l.Index(id)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil
+		return err
+	}
+
+	ls := l.Items
+	ls[idx].Done = false
+	ls[idx].CompletedAt = time.Time{}
+	ls[idx].Reminded = UrgencyNone
+	ls[idx].touch()
+
+	return nil
+}
+
+// Moves the item with the given ID to the new position. Positions start from 1.
+func (l *List) Move(id int, newPos int) error {
+	idx, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+
+	ls := l.Items
+	if newPos < 1 || newPos > len(ls) {
+		return fmt.Errorf("%w: %d is not between 1 and %d", ErrInvalidPosition, newPos, len(ls))
+	}
+
+	t := ls[idx]
+	ls = append(ls[:idx], ls[idx+1:]...)
+	ls = append(ls[:newPos-1], append([]item{t}, ls[newPos-1:]...)...)
+	l.Items = ls
+
+	return nil
+}
+
+// Returns the position in the list of the item with the given ID
+func (l *List) Index(id int) (int, error) {
+	for idx, t := range l.Items {
+		if t.ID == id {
+			return idx, nil
+		}
+	}
+
+	return -1, fmt.Errorf("%w: item %d does not exist", ErrItemNotFound, id)
+}
+
+// Records that the item has just changed by stamping UpdatedAt with the current time
+func (t *item) touch() {
+	t.UpdatedAt = time.Now()
+}
+
+// Time of the last change of the item. Items saved by older versions
+// have no UpdatedAt and changed last when they were created or completed.
+func (t *item) modified() time.Time {
+	m := t.UpdatedAt
+	if t.CreatedAt.After(m) {
+		m = t.CreatedAt
+	}
+	if t.CompletedAt.After(m) {
+		m = t.CompletedAt
+	}
+
+	return m
+}
+
+// Returns a copy of the item that does not share slices with the original
+func (t item) clone() item {
+	t.Tags = append([]string(nil), t.Tags...)
+	t.BlockedBy = append([]int(nil), t.BlockedBy...)
+	t.Repeat = t.Repeat.clone()
+	t.Attachments = append([]Attachment(nil), t.Attachments...)
+	return t
+}
+
+// Returns a copy of the list that does not share items with the original
+func (l List) Clone() List {
+	c := List{Items: make([]item, len(l.Items)), NextID: l.NextID}
+	for k, t := range l.Items {
+		c.Items[k] = t.clone()
+	}
+
+	return c
+}
+
+// Returns the ID for a new item: one more than the biggest ID the list ever had,
+// so IDs of removed items are not given again
+func (l *List) nextID() int {
+	max := 0
+	for _, t := range l.Items {
+		if t.ID > max {
+			max = t.ID
+		}
+	}
+
+	if l.NextID > max+1 {
+		return l.NextID
+	}
+
+	return max + 1
+}
+
+// Keeps the ID from being given again once its item is removed
+func (l *List) reserve(id int) {
+	if id >= l.NextID {
+		l.NextID = id + 1
+	}
+}
+
+// Assigns IDs to the items saved by older versions without them.
+// Items keep the numbers they had as positions in the list when possible.
+func (l *List) migrate() {
+	ls := l.Items
+	used := make(map[int]bool, len(ls))
+	for _, t := range ls {
+		if t.ID > 0 {
+			used[t.ID] = true
+		}
+	}
+
+	for k := range ls {
+		if ls[k].ID > 0 {
+			continue
+		}
+
+		id := k + 1
+		if used[id] {
+			id = l.nextID()
 		}
+		ls[k].ID = id
+		used[id] = true
+	}
+}
+
+// Lists are encoded as a plain array of items, as older versions saved them.
+// A list whose next ID cannot be told from its items is encoded as an object.
+func (l List) MarshalJSON() ([]byte, error) {
+	items := l.Items
+	if items == nil {
+		items = []item{}
+	}
+
+	if plain := (List{Items: items}); plain.nextID() >= l.NextID {
+		return json.Marshal(items)
+	}
+
+	return json.Marshal(struct {
+		Items  []item
+		NextID int
+	}{items, l.NextID})
+}
+
+// Decodes a list encoded as a plain array or as an object
+func (l *List) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		l.NextID = 0
+		return json.Unmarshal(data, &l.Items)
+	}
+
+	// Without the methods of List, so decoding the object does not recurse
+	type object List
+	return json.Unmarshal(data, (*object)(l))
+}
+
+// Encodes the List as JSON and saves it using the provided file name.
+// Other projects saved in the file are kept.
+func (l *List) Save(filename string) error {
+	p := Projects{}
+	if err := p.Get(filename); err != nil {
+		return err
+	}
+
+	p[DefaultProject] = *l
+	return p.Save(filename)
+}
+
+// Writes data to a temporary file next to filename and renames it over filename.
+// A crash in the middle leaves either the old or the new content, never a truncated file.
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	// Remove the temporary file unless it has been renamed
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
 		return err
 	}
 
-	if len(file) == 0 {
-		return nil
+	return os.Rename(tmp.Name(), filename)
+}
+
+// Opens the provided file name, decodes the JSON data and parses it into a List.
+// A file with several projects gives the default one.
+func (l *List) Get(filename string) error {
+	p := Projects{}
+	if err := p.Get(filename); err != nil {
+		return err
 	}
 
-	return json.Unmarshal(file, l)
+	if dl, ok := p[DefaultProject]; ok {
+		*l = dl
+	}
+
+	return nil
 }
 
-// Prints out a formatted list.
+// Prints out a formatted list. Subtasks are indented under their parents.
 func (l *List) Print(verbose bool, exludeCompleted bool) string {
 	formatted := ""
 
-	for k, t := range *l {
+	for _, r := range l.Rows(verbose, exludeCompleted) {
+		formatted += r.Text + "\n"
+	}
+
+	return formatted
+}
+
+// Line of the printed list with the ID of its item
+type Row struct {
+	ID   int
+	Text string
+}
+
+// Lines of the printed list in the order of Print
+func (l *List) Rows(verbose bool, exludeCompleted bool) []Row {
+	rows := []Row{}
+
+	for _, n := range l.tree() {
+		t, depth := n.t, n.depth
 		prefix := "  "
 		if t.Done {
 			if exludeCompleted {
@@ -100,11 +430,16 @@ func (l *List) Print(verbose bool, exludeCompleted bool) string {
 
 		dateCreated, dateCompleted := l.getDatesAsString(verbose, &t)
 
-		// Adjust the item number k to print numbers starting from 1 instead of 0
-		formatted += fmt.Sprintf("%s%d: %s    %s    %s\n", prefix, k+1, t.Task, dateCreated, dateCompleted)
+		task := t.Task + detailsAsString(&t) + l.blockersAsString(&t)
+		indent := strings.Repeat("  ", depth)
+		text := fmt.Sprintf("%s%s%d: %s    %s    %s", prefix, indent, t.ID, task, dateCreated, dateCompleted)
+		if verbose && t.focused > 0 {
+			text += "    Focused: " + formatFocus(t.focused)
+		}
+		rows = append(rows, Row{ID: t.ID, Text: text})
 	}
 
-	return formatted
+	return rows
 }
 
 func (*List) getDatesAsString(verbose bool, t *item) (string, string) {
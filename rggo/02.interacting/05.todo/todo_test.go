@@ -1,9 +1,12 @@
 package todo_test
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil" // to create temprary files
 	"os"        // to delete temporary files
+	"path/filepath"
+	"regexp"
 	"rggo/interacting/todo"
 	"testing"
 	"time"
@@ -16,8 +19,172 @@ func TestAdd(t *testing.T) {
 
 	l.Add(taskName)
 
-	if l[0].Task != taskName {
-		t.Errorf("Expected %q, got %q instead.", taskName, l[0].Task)
+	if l.Items[0].Task != taskName {
+		t.Errorf("Expected %q, got %q instead.", taskName, l.Items[0].Task)
+	}
+}
+
+// Tests adding an item with priority, due date and tags
+func TestAddWithDetails(t *testing.T) {
+	// Arrange
+	l := todo.List{}
+	due := time.Date(2026, time.November, 1, 0, 0, 0, 0, time.Local)
+
+	// Act
+	l.Add("New Task",
+		todo.WithPriority(todo.PriorityHigh),
+		todo.WithDue(due),
+		todo.WithTags("ops", " ", "home"))
+
+	// Assert
+	if l.Items[0].Priority != todo.PriorityHigh {
+		t.Errorf("Expected priority %q, got %q instead.", todo.PriorityHigh, l.Items[0].Priority)
+	}
+	if !l.Items[0].Due.Equal(due) {
+		t.Errorf("Expected due date %s, got %s instead.", due, l.Items[0].Due)
+	}
+	if len(l.Items[0].Tags) != 2 || l.Items[0].Tags[0] != "ops" || l.Items[0].Tags[1] != "home" {
+		t.Errorf("Expected tags %v, got %v instead.", []string{"ops", "home"}, l.Items[0].Tags)
+	}
+
+	expected := "  1: New Task (high) due 2026-11-01 #ops #home        \n"
+	assertString(expected, l.Print(false, false), t)
+}
+
+// Tests changing the details of an existing item
+func TestSetDetails(t *testing.T) {
+	// Arrange
+	l := todo.List{}
+	l.Add("New Task", todo.WithPriority(todo.PriorityHigh), todo.WithTags("ops"))
+
+	// Act
+	if err := l.SetDetails(1, todo.WithTags("home"), todo.WithNotes("Call first")); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	// Assert
+	if l.Items[0].Priority != todo.PriorityHigh {
+		t.Errorf("Expected priority %q to be kept, got %q.", todo.PriorityHigh, l.Items[0].Priority)
+	}
+	if len(l.Items[0].Tags) != 1 || l.Items[0].Tags[0] != "home" {
+		t.Errorf("Expected tags %v, got %v instead.", []string{"home"}, l.Items[0].Tags)
+	}
+	if l.Items[0].Notes != "Call first" {
+		t.Errorf("Expected %q, got %q instead.", "Call first", l.Items[0].Notes)
+	}
+	if err := l.SetDetails(2); !errors.Is(err, todo.ErrItemNotFound) {
+		t.Errorf("Expected error %q, got %q.", todo.ErrItemNotFound, err)
+	}
+}
+
+// Tests parsing of the priority names
+func TestParsePriority(t *testing.T) {
+	p, err := todo.ParsePriority("Medium")
+	if err != nil || p != todo.PriorityMedium {
+		t.Errorf("Expected priority %q, got %q (%v) instead.", todo.PriorityMedium, p, err)
+	}
+
+	if _, err := todo.ParsePriority("urgent"); !errors.Is(err, todo.ErrInvalidPriority) {
+		t.Errorf("Expected error %q, got %q.", todo.ErrInvalidPriority, err)
+	}
+}
+
+// Tests selecting items by tag, priority and due date
+func TestSelect(t *testing.T) {
+	// Arrange
+	l := todo.List{}
+	soon := time.Date(2026, time.October, 20, 0, 0, 0, 0, time.Local)
+	later := time.Date(2026, time.December, 1, 0, 0, 0, 0, time.Local)
+	l.Add("Task 1", todo.WithTags("ops"), todo.WithDue(soon))
+	l.Add("Task 2", todo.WithTags("ops"), todo.WithDue(later), todo.WithPriority(todo.PriorityLow))
+	l.Add("Task 3", todo.WithTags("home"), todo.WithPriority(todo.PriorityLow))
+
+	testCases := []struct {
+		name   string
+		filter todo.Filter
+		expIDs []int
+	}{
+		{name: "NoFilter", filter: todo.Filter{}, expIDs: []int{1, 2, 3}},
+		{name: "Tag", filter: todo.Filter{Tag: "OPS"}, expIDs: []int{1, 2}},
+		{name: "Priority", filter: todo.Filter{Priority: todo.PriorityLow}, expIDs: []int{2, 3}},
+		{
+			name:   "TagDueBefore",
+			filter: todo.Filter{Tag: "ops", DueBefore: time.Date(2026, time.November, 1, 0, 0, 0, 0, time.Local)},
+			expIDs: []int{1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			selected := l.Select(tc.filter)
+
+			// Assert
+			if len(selected.Items) != len(tc.expIDs) {
+				t.Fatalf("Expected %d items, got %d instead.", len(tc.expIDs), len(selected.Items))
+			}
+			for k, id := range tc.expIDs {
+				if selected.Items[k].ID != id {
+					t.Errorf("Expected ID %d, got %d instead.", id, selected.Items[k].ID)
+				}
+			}
+		})
+	}
+}
+
+// Tests searching, date ranges and sorting of the selected items
+func TestSelectQuery(t *testing.T) {
+	// Arrange
+	l := todo.List{}
+	l.Add("Buy milk")
+	l.Add("Call Bob")
+	l.Add("buy bread")
+	l.Add("Archive mail")
+	base := time.Date(2026, time.October, 1, 12, 0, 0, 0, time.Local)
+	for k := range l.Items {
+		l.Items[k].CreatedAt = base.AddDate(0, 0, k)
+	}
+	l.Complete(3)
+	l.Complete(1)
+	l.Items[0].CompletedAt = base.AddDate(0, 0, 6)
+	l.Items[2].CompletedAt = base.AddDate(0, 0, 5)
+
+	testCases := []struct {
+		name   string
+		filter todo.Filter
+		expIDs []int
+	}{
+		{name: "Search", filter: todo.Filter{Search: "BUY"}, expIDs: []int{1, 3}},
+		{name: "Regexp", filter: todo.Filter{Regexp: regexp.MustCompile(`^[A-Z]\w+ m`)}, expIDs: []int{1, 4}},
+		{name: "Open", filter: todo.Filter{Status: todo.StatusOpen}, expIDs: []int{2, 4}},
+		{name: "Done", filter: todo.Filter{Status: todo.StatusDone}, expIDs: []int{1, 3}},
+		{
+			name:   "CreatedRange",
+			filter: todo.Filter{CreatedSince: base.AddDate(0, 0, 1), CreatedUntil: base.AddDate(0, 0, 3)},
+			expIDs: []int{2, 3},
+		},
+		{
+			name:   "CompletedRange",
+			filter: todo.Filter{CompletedSince: base.AddDate(0, 0, 6)},
+			expIDs: []int{1},
+		},
+		{name: "SortAlpha", filter: todo.Filter{Sort: todo.SortAlpha}, expIDs: []int{4, 3, 1, 2}},
+		{name: "SortCompleted", filter: todo.Filter{Sort: todo.SortCompleted}, expIDs: []int{3, 1, 2, 4}},
+		{name: "SortCreated", filter: todo.Filter{Sort: todo.SortCreated}, expIDs: []int{1, 2, 3, 4}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			selected := l.Select(tc.filter)
+
+			// Assert
+			assertIDs(t, selected, tc.expIDs...)
+		})
+	}
+
+	if _, err := todo.ParseSort("priority"); !errors.Is(err, todo.ErrInvalidSort) {
+		t.Errorf("Expected error %q, got %q.", todo.ErrInvalidSort, err)
 	}
 }
 
@@ -28,11 +195,11 @@ func TestComplete(t *testing.T) {
 	taskName := "New Task"
 	l.Add(taskName)
 
-	if l[0].Task != taskName {
-		t.Errorf("Expected %q, got %q instead.", taskName, l[0].Task)
+	if l.Items[0].Task != taskName {
+		t.Errorf("Expected %q, got %q instead.", taskName, l.Items[0].Task)
 	}
 
-	if l[0].Done {
+	if l.Items[0].Done {
 		t.Errorf("New task not be completed.")
 	}
 
@@ -40,7 +207,7 @@ func TestComplete(t *testing.T) {
 	l.Complete(1)
 
 	// Assert
-	if !l[0].Done {
+	if !l.Items[0].Done {
 		t.Errorf("New task should be completed.")
 	}
 }
@@ -58,20 +225,252 @@ func TestDelete(t *testing.T) {
 		l.Add(t)
 	}
 
-	if l[0].Task != tasks[0] {
-		t.Errorf("Expected %q, got %q instead.", tasks[0], l[0].Task)
+	if l.Items[0].Task != tasks[0] {
+		t.Errorf("Expected %q, got %q instead.", tasks[0], l.Items[0].Task)
+	}
+
+	// Act
+	l.Delete(2)
+
+	// Assert
+	if len(l.Items) != 2 {
+		t.Errorf("Expected list length %d, got %d instead.", 2, len(l.Items))
+	}
+
+	if l.Items[1].Task != tasks[2] {
+		t.Errorf("Expected %q, got %q instead.", tasks[2], l.Items[1].Task)
+	}
+}
+
+// Tests the Edit method of the List type
+func TestEdit(t *testing.T) {
+	// Arrange
+	l := todo.List{}
+	l.Add("New Tsak")
+	created := l.Items[0].CreatedAt
+
+	// Act
+	if err := l.Edit(1, "New Task"); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	// Assert
+	if l.Items[0].Task != "New Task" {
+		t.Errorf("Expected %q, got %q instead.", "New Task", l.Items[0].Task)
+	}
+	if !l.Items[0].CreatedAt.Equal(created) {
+		t.Errorf("Expected creation time to be kept.")
+	}
+	if err := l.Edit(2, "Task"); !errors.Is(err, todo.ErrItemNotFound) {
+		t.Errorf("Expected error %q, got %q.", todo.ErrItemNotFound, err)
+	}
+}
+
+// Tests the Reopen method of the List type
+func TestReopen(t *testing.T) {
+	// Arrange
+	l := todo.List{}
+	l.Add("New Task")
+	l.Complete(1)
+
+	// Act
+	if err := l.Reopen(1); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	// Assert
+	if l.Items[0].Done || !l.Items[0].CompletedAt.IsZero() {
+		t.Errorf("Expected task to be reopened, got %v.", l.Items[0])
+	}
+}
+
+// Tests the Move method of the List type
+func TestMove(t *testing.T) {
+	testCases := []struct {
+		name   string
+		id     int
+		pos    int
+		expIDs []int
+		expErr error
+	}{
+		{name: "ToTop", id: 3, pos: 1, expIDs: []int{3, 1, 2}},
+		{name: "ToBottom", id: 1, pos: 3, expIDs: []int{2, 3, 1}},
+		{name: "SamePosition", id: 2, pos: 2, expIDs: []int{1, 2, 3}},
+		{name: "InvalidPosition", id: 2, pos: 4, expErr: todo.ErrInvalidPosition},
+		{name: "NotFound", id: 4, pos: 1, expErr: todo.ErrItemNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			l := todo.List{}
+			l.Add("Task 1")
+			l.Add("Task 2")
+			l.Add("Task 3")
+
+			// Act
+			err := l.Move(tc.id, tc.pos)
+
+			// Assert
+			if tc.expErr != nil {
+				if !errors.Is(err, tc.expErr) {
+					t.Errorf("Expected error %q, got %q.", tc.expErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %q.", err)
+			}
+			assertIDs(t, l, tc.expIDs...)
+		})
+	}
+}
+
+// Tests completing a parent together with its subtasks
+func TestCompleteMany(t *testing.T) {
+	// Arrange
+	l := todo.List{}
+	l.Add("Parent")
+	l.Add("Subtask")
+	l.Add("Other parent")
+	l.Add("Other subtask")
+	l.SetParent(2, 1)
+	l.SetParent(4, 3)
+
+	// Act
+	if err := l.CompleteMany([]int{1, 2}); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	// Assert
+	if !l.Items[0].Done || !l.Items[1].Done {
+		t.Errorf("Expected parent and subtask to be completed.")
+	}
+
+	if err := l.CompleteMany([]int{3}); !errors.Is(err, todo.ErrOpenSubtasks) {
+		t.Errorf("Expected error %q, got %q.", todo.ErrOpenSubtasks, err)
+	}
+}
+
+func TestDeleteMany(t *testing.T) {
+	l := todo.List{}
+	l.Add("New Task 1")
+	l.Add("New Task 2")
+	l.Add("New Task 3")
+
+	if err := l.DeleteMany([]int{1, 3}); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+	if len(l.Items) != 1 || l.Items[0].ID != 2 {
+		t.Errorf("Expected only item 2 left, got %v.", l)
+	}
+
+	if err := l.DeleteMany([]int{1}); !errors.Is(err, todo.ErrItemNotFound) {
+		t.Errorf("Expected error %q, got %q.", todo.ErrItemNotFound, err)
 	}
+}
+
+// Tests that items keep their IDs after another item is deleted
+func TestDeleteKeepsIDs(t *testing.T) {
+	// Arrange
+	l := todo.List{}
+	l.Add("New Task 1")
+	l.Add("New Task 2")
+	l.Add("New Task 3")
+
+	// Act
+	if err := l.Delete(2); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	// Assert
+	if err := l.Complete(3); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+	if !l.Items[1].Done || l.Items[1].ID != 3 {
+		t.Errorf("Expected item with ID 3 to be completed.")
+	}
+
+	if err := l.Delete(2); !errors.Is(err, todo.ErrItemNotFound) {
+		t.Errorf("Expected error %q, got %q.", todo.ErrItemNotFound, err)
+	}
+
+	l.Add("New Task 4")
+	if l.Items[2].ID != 4 {
+		t.Errorf("Expected ID %d, got %d instead.", 4, l.Items[2].ID)
+	}
+}
+
+// Tests that IDs of removed items are not given again, also after saving the list
+func TestIDsNotReused(t *testing.T) {
+	// Arrange
+	l := todo.List{}
+	l.Add("New Task 1")
+	l.Add("New Task 2")
 
 	// Act
 	l.Delete(2)
+	l.Add("New Task 3")
 
 	// Assert
-	if len(l) != 2 {
-		t.Errorf("Expected list length %d, got %d instead.", 2, len(l))
+	if l.Items[1].ID != 3 {
+		t.Errorf("Expected ID %d, got %d instead.", 3, l.Items[1].ID)
+	}
+
+	l.DeleteMany([]int{1, 3})
+	tf := filepath.Join(t.TempDir(), "todo.json")
+	if err := l.Save(tf); err != nil {
+		t.Fatalf("Error saving list to file: %s", err)
+	}
+
+	l2 := todo.List{}
+	if err := l2.Get(tf); err != nil {
+		t.Fatalf("Error getting list from file: %s", err)
+	}
+	l2.Add("New Task 4")
+	if l2.Items[0].ID != 4 {
+		t.Errorf("Expected ID %d after reload, got %d instead.", 4, l2.Items[0].ID)
+	}
+
+	// Without removed IDs the list is saved as a plain array, as before
+	if err := l2.Save(tf); err != nil {
+		t.Fatalf("Error saving list to file: %s", err)
+	}
+	data, err := os.ReadFile(tf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[0] != '[' {
+		t.Errorf("Expected a plain list, got %s.", data)
+	}
+}
+
+// Tests that items saved without IDs get them on load
+func TestGetMigratesItemsWithoutIDs(t *testing.T) {
+	// Arrange
+	tf, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("Error creating temp file: %s", err)
+	}
+	defer os.Remove(tf.Name())
+
+	legacy := `[{"Task":"Task 1","Done":false},{"Task":"Task 2","Done":true}]`
+	if _, err := tf.WriteString(legacy); err != nil {
+		t.Fatalf("Error writing temp file: %s", err)
 	}
+	tf.Close()
 
-	if l[1].Task != tasks[2] {
-		t.Errorf("Expected %q, got %q instead.", tasks[2], l[1].Task)
+	// Act
+	l := todo.List{}
+	if err := l.Get(tf.Name()); err != nil {
+		t.Fatalf("Error getting list from file: %s", err)
+	}
+
+	// Assert
+	for k, item := range l.Items {
+		if item.ID != k+1 {
+			t.Errorf("Expected ID %d, got %d instead.", k+1, item.ID)
+		}
 	}
 }
 
@@ -83,8 +482,8 @@ func TestSaveGet(t *testing.T) {
 	taskName := "New Task"
 	l1.Add(taskName)
 
-	if l1[0].Task != taskName {
-		t.Errorf("Expected %q, got %q instead.", taskName, l1[0].Task)
+	if l1.Items[0].Task != taskName {
+		t.Errorf("Expected %q, got %q instead.", taskName, l1.Items[0].Task)
 	}
 
 	tf, err := ioutil.TempFile("", "")
@@ -103,8 +502,37 @@ func TestSaveGet(t *testing.T) {
 		t.Fatalf("Error getting list from file: %s", err)
 	}
 
-	if l1[0].Task != l2[0].Task {
-		t.Fatalf("Task %q should match %q task.", l1[0].Task, l2[0].Task)
+	if l1.Items[0].Task != l2.Items[0].Task {
+		t.Fatalf("Task %q should match %q task.", l1.Items[0].Task, l2.Items[0].Task)
+	}
+
+	if l1.Items[0].ID != l2.Items[0].ID {
+		t.Fatalf("ID %d should match %d ID.", l1.Items[0].ID, l2.Items[0].ID)
+	}
+}
+
+// Tests that Save replaces the file without leaving temporary files behind
+func TestSaveAtomic(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "todo.json")
+	l := todo.List{}
+	l.Add("New Task")
+
+	// Act
+	for i := 0; i < 2; i++ {
+		if err := l.Save(filename); err != nil {
+			t.Fatalf("Error saving list to file: %s", err)
+		}
+	}
+
+	// Assert
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != "todo.json" {
+		t.Errorf("Expected only %q in the directory, got %v.", "todo.json", files)
 	}
 }
 
@@ -0,0 +1,187 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"rggo/interacting/todo"
+
+	"github.com/mum4k/termdash"
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// Rows taken by the status line with its border
+const statusHeight = 3
+
+// Full-screen interface to the todo list
+type App struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
+	controller *termdash.Controller
+	redrawCh   chan bool
+	errorCh    chan error
+	term       terminalapi.Terminal
+
+	// To prevent concurrent access to the model from the keyboard events
+	mu       sync.Mutex
+	model    *model
+	txtList  *text.Text
+	txtState *text.Text
+}
+
+// Instantiate the new application on the terminal.
+// Changes are saved in the storage as soon as they are made.
+func New(store todo.Storage, term terminalapi.Terminal) (*App, error) {
+	m, err := newModel(store)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a := &App{
+		ctx:      ctx,
+		cancel:   cancel,
+		redrawCh: make(chan bool),
+		errorCh:  make(chan error),
+		term:     term,
+		model:    m,
+	}
+
+	if a.txtList, err = text.New(text.DisableScrolling()); err != nil {
+		return nil, err
+	}
+	if a.txtState, err = text.New(text.DisableScrolling()); err != nil {
+		return nil, err
+	}
+
+	c, err := container.New(term,
+		container.SplitHorizontal(
+			container.Top(
+				container.Border(linestyle.Light),
+				container.BorderTitle("todo"),
+				container.PlaceWidget(a.txtState),
+			),
+			container.Bottom(
+				container.Border(linestyle.Light),
+				container.BorderTitle("Tasks"),
+				container.PlaceWidget(a.txtList),
+			),
+			container.SplitFixed(statusHeight),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.write(); err != nil {
+		return nil, err
+	}
+
+	a.controller, err = termdash.NewController(term, c, termdash.KeyboardSubscriber(a.keyboard))
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Run and control the application until it quits
+func (a *App) Run() error {
+	defer a.Close()
+
+	for {
+		select {
+		case <-a.redrawCh:
+			if err := a.controller.Redraw(); err != nil {
+				return err
+			}
+		case err := <-a.errorCh:
+			if err != nil {
+				return err
+			}
+		case <-a.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Stops handling the terminal events. The terminal is closed by its owner.
+func (a *App) Close() {
+	a.cancel()
+	if a.controller != nil {
+		a.controller.Close()
+		a.controller = nil
+	}
+}
+
+// Called by termdash for every key pressed
+func (a *App) keyboard(k *terminalapi.Keyboard) {
+	if err := a.handleKey(k); err != nil {
+		a.errorCh <- err
+		return
+	}
+
+	select {
+	case a.redrawCh <- true:
+	case <-a.ctx.Done():
+	}
+}
+
+// Applies the key and updates the widgets
+func (a *App) handleKey(k *terminalapi.Keyboard) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.model.handle(k.Key) {
+		a.cancel()
+		return nil
+	}
+
+	return a.write()
+}
+
+// Writes the model to the widgets. The selected row is highlighted
+// and the list scrolls to keep it visible.
+func (a *App) write() error {
+	if err := a.txtState.Write(a.model.status(), text.WriteReplace()); err != nil {
+		return err
+	}
+
+	rows := a.model.rows()
+	if len(rows) == 0 {
+		return a.txtList.Write("No tasks", text.WriteReplace())
+	}
+
+	// Borders take two rows
+	height := a.term.Size().Y - statusHeight - 2
+	if height < 1 {
+		height = 1
+	}
+
+	first := 0
+	if a.model.cursor >= height {
+		first = a.model.cursor - height + 1
+	}
+
+	a.txtList.Reset()
+	for k := first; k < len(rows) && k < first+height; k++ {
+		line := strings.TrimRight(rows[k].Text, " ") + "\n"
+
+		var err error
+		if k == a.model.cursor {
+			err = a.txtList.Write(line, text.WriteCellOpts(cell.Inverse()))
+		} else {
+			err = a.txtList.Write(line)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
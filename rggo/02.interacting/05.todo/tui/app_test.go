@@ -0,0 +1,197 @@
+package tui
+
+import (
+	"image"
+	"strings"
+	"testing"
+	"time"
+
+	"rggo/interacting/todo"
+	"rggo/interacting/todo/storage"
+
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/private/event/eventqueue"
+	"github.com/mum4k/termdash/private/faketerm"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+)
+
+// Creates the application on a fake terminal with three tasks in an in-memory storage
+func setupApp(t *testing.T) (*App, *faketerm.Terminal, todo.Storage, *eventqueue.Unbound) {
+	t.Helper()
+
+	store := storage.NewInMemory()
+	l := todo.List{}
+	l.Add("task 1")
+	l.Add("task 2")
+	l.Add("task 3")
+	if err := store.Save(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	eq := eventqueue.New()
+	ft, err := faketerm.New(image.Point{80, 12}, faketerm.WithEventQueue(eq))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := New(store, ft)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.Close)
+
+	return a, ft, store, eq
+}
+
+// Presses the keys and redraws the terminal
+func press(t *testing.T, a *App, keys ...keyboard.Key) {
+	t.Helper()
+
+	for _, k := range keys {
+		if err := a.handleKey(&terminalapi.Keyboard{Key: k}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := a.controller.Redraw(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Keys of the typed text
+func typed(s string) []keyboard.Key {
+	keys := []keyboard.Key{}
+	for _, r := range s {
+		keys = append(keys, keyboard.Key(r))
+	}
+
+	return keys
+}
+
+func assertScreen(t *testing.T, ft *faketerm.Terminal, expected ...string) {
+	t.Helper()
+
+	screen := ft.String()
+	for _, e := range expected {
+		if !strings.Contains(screen, e) {
+			t.Errorf("Expected screen to contain %q, got:\n%s", e, screen)
+		}
+	}
+}
+
+// Row of the screen with the text
+func screenRow(ft *faketerm.Terminal, s string) int {
+	for row, line := range strings.Split(ft.String(), "\n") {
+		if strings.Contains(line, s) {
+			return row
+		}
+	}
+
+	return -1
+}
+
+func TestList(t *testing.T) {
+	a, ft, _, _ := setupApp(t)
+	press(t, a)
+
+	assertScreen(t, ft, "1: task 1", "2: task 2", "3: task 3", "q quit")
+
+	// The first row is selected
+	row := screenRow(ft, "1: task 1")
+	if cell := ft.BackBuffer()[3][row]; !cell.Opts.Inverse {
+		t.Errorf("Expected first row to be highlighted.")
+	}
+}
+
+func TestToggle(t *testing.T) {
+	a, ft, store, _ := setupApp(t)
+
+	press(t, a, keyboard.KeyArrowDown, keyboard.KeySpace)
+
+	assertScreen(t, ft, "X 2: task 2")
+
+	l := todo.List{}
+	store.Load(&l)
+	if !l.Items[1].Done {
+		t.Errorf("Expected task 2 to be saved as completed.")
+	}
+
+	press(t, a, keyboard.KeySpace)
+	store.Load(&l)
+	if l.Items[1].Done {
+		t.Errorf("Expected task 2 to be reopened.")
+	}
+}
+
+func TestAddEditDelete(t *testing.T) {
+	a, ft, store, _ := setupApp(t)
+
+	t.Run("Add", func(t *testing.T) {
+		press(t, a, 'a')
+		press(t, a, typed("new task")...)
+		assertScreen(t, ft, "Add: new task_")
+
+		press(t, a, keyboard.KeyEnter)
+		assertScreen(t, ft, "4: new task")
+	})
+
+	t.Run("Edit", func(t *testing.T) {
+		// The added task is selected
+		press(t, a, 'e', keyboard.KeyBackspace2, keyboard.KeyBackspace2)
+		press(t, a, typed("x")...)
+		press(t, a, keyboard.KeyEnter)
+		assertScreen(t, ft, "4: new tax")
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		press(t, a, keyboard.KeyArrowUp, 'd')
+
+		l := todo.List{}
+		store.Load(&l)
+		if len(l.Items) != 3 || l.Items[2].Task != "new tax" {
+			t.Errorf("Expected task 3 to be deleted, got %v.", l)
+		}
+	})
+}
+
+func TestFilter(t *testing.T) {
+	a, ft, _, _ := setupApp(t)
+
+	press(t, a, '/', '2')
+	if screenRow(ft, "task 1") != -1 || screenRow(ft, "task 3") != -1 {
+		t.Errorf("Expected only task 2 while typing the filter, got:\n%s", ft)
+	}
+	assertScreen(t, ft, "2: task 2", "Filter: 2_")
+
+	press(t, a, keyboard.KeyEnter)
+	assertScreen(t, ft, "Filter: 2 |")
+
+	press(t, a, '/', keyboard.KeyEsc)
+	assertScreen(t, ft, "1: task 1", "3: task 3")
+}
+
+// Tests that the keyboard events of the terminal reach the application
+func TestRunQuit(t *testing.T) {
+	a, _, store, eq := setupApp(t)
+
+	errCh := make(chan error)
+	go func() { errCh <- a.Run() }()
+
+	eq.Push(&terminalapi.Keyboard{Key: keyboard.KeySpace})
+	eq.Push(&terminalapi.Keyboard{Key: 'q'})
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("Expected no error, got %q.", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the application to quit.")
+	}
+
+	l := todo.List{}
+	store.Load(&l)
+	if !l.Items[0].Done {
+		t.Errorf("Expected task 1 to be completed.")
+	}
+}
@@ -0,0 +1,216 @@
+package tui
+
+import (
+	"strings"
+
+	"rggo/interacting/todo"
+
+	"github.com/mum4k/termdash/keyboard"
+)
+
+// What the typed keys do
+type mode int
+
+const (
+	modeList mode = iota
+	modeAdd
+	modeEdit
+	modeFilter
+)
+
+// State of the interface, independent of the widgets
+type model struct {
+	store todo.Storage
+	list  todo.List
+	// Text the shown tasks contain
+	filter string
+	// Index of the selected row
+	cursor int
+	mode   mode
+	// Text typed in the add, edit and filter modes
+	input []rune
+	// Result of the last operation
+	message string
+}
+
+func newModel(store todo.Storage) (*model, error) {
+	m := &model{store: store}
+	if err := store.Load(&m.list); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Rows of the tasks matching the filter
+func (m *model) rows() []todo.Row {
+	selected := m.list.Select(todo.Filter{Search: m.filter})
+	return selected.Rows(false, false)
+}
+
+// ID of the selected item, 0 if no item is shown
+func (m *model) selected() int {
+	rows := m.rows()
+	if len(rows) == 0 {
+		return 0
+	}
+
+	return rows[m.cursor].ID
+}
+
+// Applies the key to the state. Returns false if the interface must quit.
+func (m *model) handle(k keyboard.Key) bool {
+	if m.mode != modeList {
+		m.handleInput(k)
+		return true
+	}
+
+	m.message = ""
+	switch k {
+	case keyboard.KeyArrowUp:
+		m.cursor--
+	case keyboard.KeyArrowDown:
+		m.cursor++
+	case keyboard.KeySpace, keyboard.KeyEnter:
+		m.toggle()
+	case 'a':
+		m.mode, m.input = modeAdd, nil
+	case 'e':
+		if id := m.selected(); id > 0 {
+			idx, _ := m.list.Index(id)
+			m.mode, m.input = modeEdit, []rune(m.list.Items[idx].Task)
+		}
+	case 'd', keyboard.KeyDelete:
+		if id := m.selected(); id > 0 {
+			m.update(func(l *todo.List) error { return l.Delete(id) })
+		}
+	case '/':
+		m.mode, m.input = modeFilter, []rune(m.filter)
+	case 'q', keyboard.KeyEsc, keyboard.KeyCtrlC:
+		return false
+	}
+
+	m.clampCursor()
+	return true
+}
+
+// Edits the typed text. The filter is applied as it is typed.
+func (m *model) handleInput(k keyboard.Key) {
+	switch k {
+	case keyboard.KeyEnter:
+		m.submit()
+		m.mode = modeList
+	case keyboard.KeyEsc:
+		if m.mode == modeFilter {
+			m.filter = ""
+		}
+		m.mode = modeList
+	case keyboard.KeyBackspace, keyboard.KeyBackspace2:
+		if len(m.input) > 0 {
+			m.input = m.input[:len(m.input)-1]
+		}
+	default:
+		// Printable characters only
+		if k >= keyboard.KeySpace {
+			m.input = append(m.input, rune(k))
+		}
+	}
+
+	if m.mode == modeFilter {
+		m.filter = string(m.input)
+	}
+
+	m.clampCursor()
+}
+
+// Applies the typed text
+func (m *model) submit() {
+	text := strings.TrimSpace(string(m.input))
+	if text == "" {
+		return
+	}
+
+	switch m.mode {
+	case modeAdd:
+		id := 0
+		m.update(func(l *todo.List) error {
+			l.Add(text)
+			id = l.Items[len(l.Items)-1].ID
+			return nil
+		})
+		m.moveTo(id)
+	case modeEdit:
+		id := m.selected()
+		m.update(func(l *todo.List) error { return l.Edit(id, text) })
+	}
+}
+
+// Completes the selected item or reopens it if it is done
+func (m *model) toggle() {
+	id := m.selected()
+	if id == 0 {
+		return
+	}
+
+	idx, _ := m.list.Index(id)
+	if m.list.Items[idx].Done {
+		m.update(func(l *todo.List) error { return l.Reopen(id) })
+		return
+	}
+
+	m.update(func(l *todo.List) error { return l.Complete(id) })
+}
+
+// Saves the change in the storage and reloads the list, as other processes may share it
+func (m *model) update(fn func(l *todo.List) error) {
+	if err := todo.Update(m.store, fn); err != nil {
+		m.message = err.Error()
+	}
+
+	if err := m.store.Load(&m.list); err != nil {
+		m.message = err.Error()
+	}
+}
+
+// Selects the row of the item if it is shown
+func (m *model) moveTo(id int) {
+	for k, r := range m.rows() {
+		if r.ID == id {
+			m.cursor = k
+			return
+		}
+	}
+}
+
+func (m *model) clampCursor() {
+	n := len(m.rows())
+	if m.cursor >= n {
+		m.cursor = n - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// Text of the status line
+func (m *model) status() string {
+	switch m.mode {
+	case modeAdd:
+		return "Add: " + string(m.input) + "_"
+	case modeEdit:
+		return "Edit: " + string(m.input) + "_"
+	case modeFilter:
+		return "Filter: " + string(m.input) + "_"
+	}
+
+	if m.message != "" {
+		return m.message
+	}
+
+	help := "Up/Down move, Space done, a add, e edit, d delete, / filter, q quit"
+	if m.filter != "" {
+		return "Filter: " + m.filter + " | " + help
+	}
+
+	return help
+}
@@ -9,7 +9,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"testing"
+
+	"github.com/spf13/viper"
 )
 
 func TestListAction(t *testing.T) {
@@ -31,7 +34,7 @@ func TestListAction(t *testing.T) {
 		{
 			name:     "Results",
 			expError: nil,
-			expOut:   "-  1  Task 1\n-  2  Task 2\n",
+			expOut:   "-  1  Task 1\n-  3  Task 2\n",
 			resp:     testResp["resultsMany"],
 		},
 		{
@@ -85,6 +88,38 @@ func TestListAction(t *testing.T) {
 	}
 }
 
+// Tests that list follows the next links until the last page
+func TestListActionPages(t *testing.T) {
+	pages := map[string]string{
+		"": `{"results": [{"ID": 1, "Task": "Task 1"}], "total_results": 3,
+			"next": "/todo?limit=1&offset=1"}`,
+		"1": `{"results": [{"ID": 2, "Task": "Task 2", "Done": true}], "total_results": 3,
+			"next": "/todo?limit=1&offset=2", "prev": "/todo?limit=1&offset=0"}`,
+		"2": `{"results": [{"ID": 4, "Task": "Task 3"}], "total_results": 3,
+			"prev": "/todo?limit=1&offset=1"}`,
+	}
+
+	url, cleanup := mockServer(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("limit") == "" {
+				t.Errorf("Expected the client to ask for a page size, got %q.", r.URL)
+			}
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintln(w, pages[r.URL.Query().Get("offset")])
+		})
+	defer cleanup()
+
+	var out bytes.Buffer
+	if err := listAction(&out, url); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	expOut := "-  1  Task 1\nX  2  Task 2\n-  4  Task 3\n"
+	if expOut != out.String() {
+		t.Errorf("Expected output %q, got %q.", expOut, out.String())
+	}
+}
+
 func TestViewAction(t *testing.T) {
 	testCases := []struct {
 		// Test case name
@@ -111,6 +146,19 @@ Completed:    No
 			resp: testResp["resultsOne"],
 			id:   "1",
 		},
+		{
+			name:     "ResultsNotes",
+			expError: nil,
+			expOut: `Task:         Task 1
+Created at:   Oct/28 @08:23
+Completed:    No
+Notes:        Line 1
+              Line 2
+Attachments:  /tmp/a.txt (6 bytes, sha256 5891b5b522d5)
+`,
+			resp: testResp["resultsNotes"],
+			id:   "1",
+		},
 		{
 			name:     "NotFound",
 			expError: ErrNotFound,
@@ -236,7 +284,7 @@ func TestCompleteAction(t *testing.T) {
 
 	// Execute complete test
 	var out bytes.Buffer
-	if err := completeAction(&out, url, arg); err != nil {
+	if err := completeAction(&out, url, arg, ""); err != nil {
 		t.Fatalf("Expected no error, got %q.", err)
 	}
 	if expOut != out.String() {
@@ -268,10 +316,79 @@ func TestDelAction(t *testing.T) {
 
 	// Execute Del test
 	var out bytes.Buffer
-	if err := delAction(&out, url, arg); err != nil {
+	if err := delAction(&out, url, arg, ""); err != nil {
 		t.Fatalf("Expected no error, got %q.", err)
 	}
 	if expOut != out.String() {
 		t.Errorf("Expected output %q, got %q.", expOut, out.String())
 	}
 }
+
+// Tests that the configured token is sent with the requests
+func TestToken(t *testing.T) {
+	viper.Set("token", "secret-key")
+	defer viper.Set("token", "")
+
+	url, cleanup := mockServer(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer secret-key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprintln(w, "missing bearer token")
+				return
+			}
+			w.WriteHeader(testResp["resultsOne"].Status)
+			fmt.Fprintln(w, testResp["resultsOne"].Body)
+		})
+	defer cleanup()
+
+	var out bytes.Buffer
+	if err := listAction(&out, url); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	viper.Set("token", "wrong-key")
+	err := listAction(&out, url)
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected error %q, got %q.", ErrUnauthorized, err)
+	}
+}
+
+// Tests that changes sent with an old version fail with a conflict
+func TestVersionConflict(t *testing.T) {
+	version := `"v2"`
+
+	url, cleanup := mockServer(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", version)
+			if r.Method == http.MethodGet {
+				w.WriteHeader(testResp["resultsOne"].Status)
+				fmt.Fprintln(w, testResp["resultsOne"].Body)
+				return
+			}
+			if m := r.Header.Get("If-Match"); m != "" && m != version {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				fmt.Fprintln(w, "Precondition Failed")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	defer cleanup()
+
+	var out bytes.Buffer
+	if err := viewAction(&out, url, "1"); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+	if !strings.Contains(out.String(), "Version:      \"v2\"\n") {
+		t.Errorf("Expected the version in the output, got %q.", out.String())
+	}
+
+	if err := completeAction(&out, url, "1", `"v1"`); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected error %q, got %q.", ErrConflict, err)
+	}
+	if err := delAction(&out, url, "1", `"v1"`); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected error %q, got %q.", ErrConflict, err)
+	}
+	if err := delAction(&out, url, "1", version); err != nil {
+		t.Errorf("Expected no error, got %q.", err)
+	}
+}
@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
+
+	"github.com/spf13/viper"
 )
 
 const (
@@ -16,6 +19,8 @@ const (
 	ContentType            = "Content-Type"
 	ContentTextPlain       = "text/plain"
 	ContentApplicationJson = "application/json"
+	// Number of items requested at once when listing all of them
+	pageSize = 100
 )
 
 var (
@@ -29,62 +34,91 @@ var (
 	ErrInvalid = errors.New("Invalid data")
 	//lint:ignore ST1005 Ignore warning
 	ErrNotNumber = errors.New("Not a number")
+	//lint:ignore ST1005 Ignore warning
+	ErrUnauthorized = errors.New("Unauthorized")
+	//lint:ignore ST1005 Ignore warning
+	ErrConflict = errors.New("Conflict")
 )
 
 type item struct {
+	ID          int
 	Task        string
 	Done        bool
 	CreatedAt   time.Time
 	CompletedAt time.Time
+	Notes       string
+	Attachments []attachment
+	// ETag of the item. Changes sent with it fail if the item changed since.
+	Version string `json:"-"`
+}
+
+// Reference to a file attached to an item
+type attachment struct {
+	Path   string
+	Size   int64
+	SHA256 string
 }
 
 type response struct {
 	Results      []item `json:"results"`
 	Date         int64  `json:"date"`
 	TotalResults int    `json:"total_results"`
+	// Links to the other pages of the results
+	Next string `json:"next"`
+	Prev string `json:"prev"`
+	// ETag of the results
+	ETag string `json:"-"`
 }
 
+// Sends the token from the --token flag, the TODO_TOKEN environment variable
+// or the config file with every request
 func newClient() *http.Client {
 	c := &http.Client{
 		Timeout: 10 * time.Second,
 	}
+	if token := viper.GetString("token"); token != "" {
+		c.Transport = &tokenTransport{token: token, base: http.DefaultTransport}
+	}
 	return c
 }
 
-func getItems(url string) ([]item, error) {
+// Adds the bearer token to the requests
+type tokenTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	// Round trippers must not change the request
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(r)
+}
+
+func getPage(url string) (response, error) {
 	r, err := newClient().Get(url)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %s", ErrConnection, err)
+		return response{}, fmt.Errorf("%w: %s", ErrConnection, err)
 	}
 	defer r.Body.Close()
 
-	if r.StatusCode != http.StatusOK {
-		msg, err := io.ReadAll(r.Body)
-		if err != nil {
-			//lint:ignore ST1005 Ignore warning
-			return nil, fmt.Errorf("Cannot read body: %w", err)
-		}
-		err = ErrInvalidResponse
-		if r.StatusCode == http.StatusNotFound {
-			err = ErrNotFound
-		}
-		return nil, fmt.Errorf("%w: %s", err, msg)
+	if err := checkStatus(r, http.StatusOK); err != nil {
+		return response{}, err
 	}
 
 	var resp response
 	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
-		return nil, err
-	}
-
-	if resp.TotalResults == 0 {
-		return nil, fmt.Errorf("%w: No results found", ErrNotFound)
+		return response{}, err
 	}
+	resp.ETag = r.Header.Get("ETag")
 
-	return resp.Results, nil
+	return resp, nil
 }
 
+// Sends the request. A version makes the server apply it only if the item
+// still has that version; otherwise it fails with ErrConflict.
 func sendRequest(
-	url, method, contentType string, expStatus int, body io.Reader) error {
+	url, method, contentType, version string, expStatus int, body io.Reader) error {
 	request, err := http.NewRequest(method, url, body)
 	if err != nil {
 		return err
@@ -93,6 +127,9 @@ func sendRequest(
 	if contentType != "" {
 		request.Header.Set(ContentType, contentType)
 	}
+	if version != "" {
+		request.Header.Set("If-Match", version)
+	}
 
 	response, err := newClient().Do(request)
 	if err != nil {
@@ -100,40 +137,84 @@ func sendRequest(
 	}
 	defer response.Body.Close()
 
-	if response.StatusCode != expStatus {
-		msg, err := io.ReadAll(response.Body)
+	return checkStatus(response, expStatus)
+}
+
+// Returns an error with the message of the server unless the response has the expected status
+func checkStatus(r *http.Response, expStatus int) error {
+	if r.StatusCode == expStatus {
+		return nil
+	}
+
+	msg, err := io.ReadAll(r.Body)
+	if err != nil {
+		//lint:ignore ST1005 Ignore warning
+		return fmt.Errorf("Cannot read body: %w", err)
+	}
+
+	err = ErrInvalidResponse
+	switch r.StatusCode {
+	case http.StatusNotFound:
+		err = ErrNotFound
+	case http.StatusUnauthorized:
+		err = ErrUnauthorized
+	case http.StatusPreconditionFailed:
+		return fmt.Errorf("%w: Item changed on the server since it was read, view it and try again: %s",
+			ErrConflict, bytes.TrimSpace(msg))
+	case http.StatusConflict:
+		err = ErrConflict
+	}
+	return fmt.Errorf("%w: %s", err, msg)
+}
+
+// Gets the items page by page, following the next links.
+// Servers without pagination send all items in the first page.
+func getAll(apiRoot string) ([]item, error) {
+	base, err := url.Parse(apiRoot)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrConnection, err)
+	}
+
+	u := fmt.Sprintf("%s/todo?limit=%d", apiRoot, pageSize)
+	items := []item{}
+	for {
+		resp, err := getPage(u)
 		if err != nil {
-			//lint:ignore ST1005 Ignore warning
-			return fmt.Errorf("Cannot read body: %w", err)
+			return nil, err
+		}
+		items = append(items, resp.Results...)
+
+		if resp.Next == "" || len(resp.Results) == 0 {
+			break
 		}
-		err = ErrInvalidResponse
-		if response.StatusCode == http.StatusNotFound {
-			err = ErrNotFound
+		next, err := url.Parse(resp.Next)
+		if err != nil {
+			return nil, fmt.Errorf("%w: Invalid next link: %s", ErrInvalidResponse, err)
 		}
-		return fmt.Errorf("%w: %s", err, msg)
+		u = base.ResolveReference(next).String()
 	}
 
-	return nil
-}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: No results found", ErrNotFound)
+	}
 
-func getAll(apiRoot string) ([]item, error) {
-	u := fmt.Sprintf("%s/todo", apiRoot)
-	return getItems(u)
+	return items, nil
 }
 
 func getOne(apiRoot string, id int) (item, error) {
 	url := fmt.Sprintf("%s/todo/%d", apiRoot, id)
 
-	items, err := getItems(url)
+	resp, err := getPage(url)
 	if err != nil {
 		return item{}, err
 	}
 
-	if len(items) != 1 {
+	if len(resp.Results) != 1 {
 		return item{}, fmt.Errorf("%w: Invalid results", ErrInvalid)
 	}
 
-	return items[0], nil
+	resp.Results[0].Version = resp.ETag
+	return resp.Results[0], nil
 }
 
 func addItem(apiRoot, task string) error {
@@ -149,15 +230,15 @@ func addItem(apiRoot, task string) error {
 		return err
 	}
 
-	return sendRequest(url, http.MethodPost, ContentApplicationJson, http.StatusCreated, &body)
+	return sendRequest(url, http.MethodPost, ContentApplicationJson, "", http.StatusCreated, &body)
 }
 
-func completeItem(apiRoot string, id int) error {
+func completeItem(apiRoot string, id int, version string) error {
 	url := fmt.Sprintf("%s/todo/%d?complete", apiRoot, id)
-	return sendRequest(url, http.MethodPatch, "", http.StatusNoContent, nil)
+	return sendRequest(url, http.MethodPatch, "", version, http.StatusNoContent, nil)
 }
 
-func deleteItem(apiRoot string, id int) error {
+func deleteItem(apiRoot string, id int, version string) error {
 	url := fmt.Sprintf("%s/todo/%d", apiRoot, id)
-	return sendRequest(url, http.MethodDelete, "", http.StatusNoContent, nil)
+	return sendRequest(url, http.MethodDelete, "", version, http.StatusNoContent, nil)
 }
@@ -17,21 +17,27 @@ var completeCmd = &cobra.Command{
 	Args:         cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		apiRoot := viper.GetString("api-root")
-		return completeAction(os.Stdout, apiRoot, args[0])
+		version, err := cmd.Flags().GetString("version")
+		if err != nil {
+			return err
+		}
+
+		return completeAction(os.Stdout, apiRoot, args[0], version)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(completeCmd)
+	completeCmd.Flags().String("version", "", "Version of the item shown by view. Fails if the item changed since")
 }
 
-func completeAction(out io.Writer, apiRoot, arg string) error {
+func completeAction(out io.Writer, apiRoot, arg, version string) error {
 	id, err := strconv.Atoi(arg)
 	if err != nil {
 		return fmt.Errorf("%w: Item id must be a number", ErrNotNumber)
 	}
 
-	if err := completeItem(apiRoot, id); err != nil {
+	if err := completeItem(apiRoot, id, version); err != nil {
 		return err
 	}
 
@@ -16,21 +16,27 @@ var delCmd = &cobra.Command{
 	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		apiRoot := viper.GetString("api-root")
-		return delAction(os.Stdout, apiRoot, args[0])
+		version, err := cmd.Flags().GetString("version")
+		if err != nil {
+			return err
+		}
+
+		return delAction(os.Stdout, apiRoot, args[0], version)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(delCmd)
+	delCmd.Flags().String("version", "", "Version of the item shown by view. Fails if the item changed since")
 }
 
-func delAction(out io.Writer, apiRoot, arg string) error {
+func delAction(out io.Writer, apiRoot, arg, version string) error {
 	id, err := strconv.Atoi(arg)
 	if err != nil {
 		return fmt.Errorf("%w: Item id must be a number", ErrNotNumber)
 	}
 
-	if err := deleteItem(apiRoot, id); err != nil {
+	if err := deleteItem(apiRoot, id, version); err != nil {
 		return err
 	}
 
@@ -107,7 +107,7 @@ func TestIntegration(t *testing.T) {
 	// Step 4.
 	t.Run("4.CompleteTask", func(t *testing.T) {
 		var out bytes.Buffer
-		if err := completeAction(&out, apiRoot, taskId); err != nil {
+		if err := completeAction(&out, apiRoot, taskId, ""); err != nil {
 			t.Fatalf("Expected no error, got %q.", err)
 		}
 
@@ -146,7 +146,7 @@ func TestIntegration(t *testing.T) {
 	// Step 6.
 	t.Run("6.DeleteTask", func(t *testing.T) {
 		var out bytes.Buffer
-		if err := delAction(&out, apiRoot, taskId); err != nil {
+		if err := delAction(&out, apiRoot, taskId, ""); err != nil {
 			t.Fatalf("Expected no error, got %q.", err)
 		}
 
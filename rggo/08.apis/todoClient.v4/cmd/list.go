@@ -68,7 +68,12 @@ func printAll(out io.Writer, items []item, listActiveOnly bool) error {
 		if listActiveOnly && done == "X" {
 			continue
 		}
-		fmt.Fprintf(w, "%s\t%d\t%s\t\n", done, index+1, item.Task)
+		// Servers without stable IDs identify items by their position
+		id := item.ID
+		if id == 0 {
+			id = index + 1
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t\n", done, id, item.Task)
 	}
 
 	// Flush the output to the io.Writer
@@ -14,12 +14,14 @@ var testResp = map[string]struct {
 		Body: `{
 			"results": [
 				{
+					"ID": 1,
 					"Task": "Task 1",
 					"Done": false,
 					"CreatedAt": "2019-10-28T08:23:38.310097076-04:00",
 					"CompletedAt": "0001-01-01T00:00:00Z"
 				},
 				{
+					"ID": 3,
 					"Task": "Task 2",
 					"Done": false,
 					"CreatedAt": "2019-10-28T08:23:38.323447798-04:00",
@@ -35,6 +37,7 @@ var testResp = map[string]struct {
 		Body: `{
 			"results": [
 				{
+					"ID": 1,
 					"Task": "Task 1",
 					"Done": false,
 					"CreatedAt": "2019-10-28T08:23:38.310097076-04:00",
@@ -45,6 +48,26 @@ var testResp = map[string]struct {
 			"total_results": 1
 		}`,
 	},
+	"resultsNotes": {
+		Status: http.StatusOK,
+		Body: `{
+			"results": [
+				{
+					"ID": 1,
+					"Task": "Task 1",
+					"Done": false,
+					"CreatedAt": "2019-10-28T08:23:38.310097076-04:00",
+					"CompletedAt": "0001-01-01T00:00:00Z",
+					"Notes": "Line 1\nLine 2",
+					"Attachments": [
+						{"Path": "/tmp/a.txt", "Size": 6, "SHA256": "5891b5b522d5"}
+					]
+				}
+				],
+			"date": 1572265440,
+			"total_results": 1
+		}`,
+	},
 	"noResults": {
 		Status: http.StatusOK,
 		Body: `{
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 	"strings"
 
@@ -26,12 +27,17 @@ func Execute() {
 }
 
 func init() {
+	cobra.OnInitialize(initConfig)
+
 	rootCmd.PersistentFlags().StringVar(
 		&cfgFile, "config", "", "config file (default is $HOME/.todoClient.yaml)")
 
 	rootCmd.PersistentFlags().String(
 		"api-root", "http://localhost:8080", "Todo API URL")
 
+	rootCmd.PersistentFlags().String(
+		"token", "", "Bearer token or API key of the Todo API")
+
 	// Environment variable TODO_API_ROOT
 	replacer := strings.NewReplacer("-", "_")
 	viper.SetEnvKeyReplacer(replacer)
@@ -39,4 +45,33 @@ func init() {
 
 	// Bind an environment variable TODO_API_ROOT
 	viper.BindPFlag("api-root", rootCmd.PersistentFlags().Lookup("api-root"))
+	// Bind an environment variable TODO_TOKEN
+	viper.BindPFlag("token", rootCmd.PersistentFlags().Lookup("token"))
+}
+
+// initConfig reads in config file and ENV variables if set.
+func initConfig() {
+	if cfgFile != "" {
+		// Use config file from the flag.
+		viper.SetConfigFile(cfgFile)
+	} else {
+		// Find home directory.
+		home, err := os.UserHomeDir()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// Search config in home directory with name ".todoClient" (without extension).
+		viper.AddConfigPath(home)
+		viper.SetConfigName(".todoClient")
+	}
+
+	// Read in environment variables that match
+	viper.AutomaticEnv()
+
+	// If config file is found, read it in. Output is kept clean for the listings.
+	if err := viper.ReadInConfig(); err == nil {
+		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+	}
 }
@@ -5,6 +5,7 @@ import (
 	"io"             // To use io.Writer interface
 	"os"             // To use os.Stdout for output
 	"strconv"        // To convert string to integer
+	"strings"        // To split the notes into lines
 	"text/tabwriter" // To print tabulated data
 
 	"github.com/spf13/cobra"
@@ -52,8 +53,29 @@ func printOne(out io.Writer, i item) error {
 	if i.Done {
 		fmt.Fprintf(w, "Completed:\t%s\n", "Yes")
 		fmt.Fprintf(w, "Completed at:\t%s\n", i.CompletedAt.Format(timeFormat))
-		return w.Flush()
+	} else {
+		fmt.Fprintf(w, "Completed:\t%s\n", "No")
 	}
-	fmt.Fprintf(w, "Completed:\t%s\n", "No")
+
+	// Every line of the notes and every attachment is aligned with the first one
+	label := "Notes:"
+	if i.Notes != "" {
+		for _, line := range strings.Split(i.Notes, "\n") {
+			fmt.Fprintf(w, "%s\t%s\n", label, line)
+			label = ""
+		}
+	}
+
+	label = "Attachments:"
+	for _, a := range i.Attachments {
+		fmt.Fprintf(w, "%s\t%s (%d bytes, sha256 %s)\n", label, a.Path, a.Size, a.SHA256)
+		label = ""
+	}
+
+	// Passed to complete and del with --version to detect concurrent changes
+	if i.Version != "" {
+		fmt.Fprintf(w, "Version:\t%s\n", i.Version)
+	}
+
 	return w.Flush()
 }
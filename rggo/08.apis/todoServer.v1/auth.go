@@ -0,0 +1,192 @@
+package main
+
+import (
+	"crypto/hmac"           // To sign the bearer tokens
+	"crypto/sha256"         // Hash of the token signatures
+	"crypto/subtle"         // To compare API keys in constant time
+	"encoding/base64"       // To encode the token signatures
+	"encoding/json"         // To read the auth config file
+	"errors"                // To define and handle errors
+	"fmt"                   // To format the tokens
+	"net/http"              // To deal with HTTP requests and responses
+	"os"                    // To read the auth config file
+	"path/filepath"         // To name the storage and the blobs of each user
+	"rggo/interacting/todo" // To validate the user names
+	"strconv"               // To format the expiry of the tokens
+	"strings"               // To parse the Authorization header
+	"sync"                  // To guard the handlers of the users
+	"time"                  // To expire the tokens
+)
+
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// Settings of the authentication, read from a JSON file like:
+//
+//	{"secret": "...", "keys": {"<api key>": "alice"}}
+type authConfig struct {
+	// Key signing the bearer tokens. Without it only API keys are accepted.
+	Secret string `json:"secret"`
+	// Static API keys and the users they authenticate
+	Keys map[string]string `json:"keys"`
+}
+
+func loadAuthConfig(filename string) (*authConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &authConfig{}
+	if err := json.Unmarshal(data, a); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	if a.Secret == "" && len(a.Keys) == 0 {
+		return nil, fmt.Errorf("%s: %w: no secret and no keys", filename, ErrInvalidData)
+	}
+
+	// User names name their storage
+	for _, user := range a.Keys {
+		if err := todo.ValidateProject(user); err != nil {
+			return nil, fmt.Errorf("%s: %w: user %q", filename, ErrInvalidData, user)
+		}
+	}
+
+	return a, nil
+}
+
+// Returns a bearer token of the user valid until the expiry time.
+// Tokens look like user.expiry.signature, with the expiry in Unix seconds.
+func (a *authConfig) issueToken(user string, expires time.Time) (string, error) {
+	if a.Secret == "" {
+		return "", fmt.Errorf("%w: no secret to sign tokens", ErrInvalidData)
+	}
+	if err := todo.ValidateProject(user); err != nil {
+		return "", fmt.Errorf("%w: user %q", ErrInvalidData, user)
+	}
+
+	payload := user + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + a.sign(payload), nil
+}
+
+func (a *authConfig) sign(payload string) string {
+	mac := hmac.New(sha256.New, []byte(a.Secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Returns the user authenticated by the bearer token of the request,
+// which is an API key or a signed token
+func (a *authConfig) authenticate(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	token := strings.TrimPrefix(header, "Bearer ")
+	if token == header || token == "" {
+		return "", fmt.Errorf("%w: missing bearer token", ErrUnauthorized)
+	}
+
+	for key, user := range a.Keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(token)) == 1 {
+			return user, nil
+		}
+	}
+
+	if a.Secret == "" {
+		return "", fmt.Errorf("%w: unknown API key", ErrUnauthorized)
+	}
+
+	return a.verify(token, time.Now())
+}
+
+// Checks the signature and the expiry of the token and returns its user
+func (a *authConfig) verify(token string, now time.Time) (string, error) {
+	i := strings.LastIndex(token, ".")
+	if i < 0 {
+		return "", fmt.Errorf("%w: %w", ErrUnauthorized, ErrInvalidToken)
+	}
+	payload, signature := token[:i], token[i+1:]
+
+	if !hmac.Equal([]byte(signature), []byte(a.sign(payload))) {
+		return "", fmt.Errorf("%w: %w", ErrUnauthorized, ErrInvalidToken)
+	}
+
+	j := strings.LastIndex(payload, ".")
+	if j < 0 {
+		return "", fmt.Errorf("%w: %w", ErrUnauthorized, ErrInvalidToken)
+	}
+	user := payload[:j]
+
+	expires, err := strconv.ParseInt(payload[j+1:], 10, 64)
+	if err != nil || todo.ValidateProject(user) != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnauthorized, ErrInvalidToken)
+	}
+	if !now.Before(time.Unix(expires, 0)) {
+		return "", fmt.Errorf("%w: token expired", ErrUnauthorized)
+	}
+
+	return user, nil
+}
+
+// Authenticates the requests and serves each user with the handler of their own lists.
+// The handlers are created on the first request of each user.
+func newAuthHandler(a *authConfig, handlerOf func(user string) (http.Handler, error)) http.Handler {
+	mu := sync.Mutex{}
+	handlers := map[string]http.Handler{}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reply := replyError
+		if strings.HasPrefix(r.URL.Path, "/v2/") {
+			reply = replyJSONError
+		}
+
+		user, err := a.authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="todo"`)
+			reply(w, r, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		mu.Lock()
+		h, ok := handlers[user]
+		if !ok {
+			if h, err = handlerOf(user); err == nil {
+				handlers[user] = h
+			}
+		}
+		mu.Unlock()
+
+		if err != nil {
+			reply(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Returns a function opening the lists of a user in their own storage, named after
+// the storage URI with the user before the extension, like todoServer.alice.json.
+// The attachments of each user are kept in their own directory of blobs.
+func userHandlers(uri, blobs string,
+	open func(uri string) (todo.ProjectStorage, error)) func(user string) (http.Handler, error) {
+
+	return func(user string) (http.Handler, error) {
+		ext := filepath.Ext(uri)
+		store, err := open(strings.TrimSuffix(uri, ext) + "." + user + ext)
+		if err != nil {
+			return nil, err
+		}
+
+		dir := ""
+		if blobs != "" {
+			dir = filepath.Join(blobs, user)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return nil, err
+			}
+		}
+
+		return newMux(store, dir), nil
+	}
+}
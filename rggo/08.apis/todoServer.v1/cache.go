@@ -0,0 +1,426 @@
+package main
+
+import (
+	"errors"                        // To join the errors of several projects
+	"log/slog"                      // To log the failed writes
+	"rggo/interacting/todo"         // todo application
+	"rggo/interacting/todo/storage" // To open the cached storages
+	"sort"                          // To sort the project names
+	"sync"                          // To guard the cached lists
+	"time"                          // To delay the writes
+)
+
+// Keeps the lists of a storage in memory, so requests do not read the storage,
+// and writes the changed lists behind: changes made within the delay are
+// written together. A zero delay writes every change before Save returns.
+// The history of the backend is written with the lists.
+// Close writes the pending changes. The lists are read once, and other
+// processes may change the storage meanwhile: a list changed since it was
+// read or written is merged with the stored one by todo.Reconcile when it is
+// written again. Items changed on both sides keep the version of the server.
+type cachedStorage struct {
+	backend todo.ProjectStorage
+	delay   time.Duration
+
+	mu    sync.RWMutex
+	lists map[string]todo.List
+	dirty map[string]bool
+	timer *time.Timer
+	// Histories saved since the last write
+	histories map[string]*todo.History
+	// Stored versions of the lists when they were last read or written
+	versions map[string]version
+	// Delay of the next retry of failed writes, zero without failures
+	retry time.Duration
+
+	// Serializes the writes to the backend
+	flushMu sync.Mutex
+}
+
+func newCachedStorage(backend todo.ProjectStorage, delay time.Duration) *cachedStorage {
+	return &cachedStorage{
+		backend:   backend,
+		delay:     delay,
+		lists:     map[string]todo.List{},
+		dirty:     map[string]bool{},
+		histories: map[string]*todo.History{},
+		versions:  map[string]version{},
+	}
+}
+
+// Delays of the retries of failed writes, doubled after each failure
+var (
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+)
+
+// Stored list as the cache last read or wrote it
+type version struct {
+	etag string
+	at   time.Time
+}
+
+// List of one project of the cached storage
+type cachedProject struct {
+	c    *cachedStorage
+	name string
+}
+
+func (p *cachedProject) Load(l *todo.List) error {
+	return p.c.load(p.name, l)
+}
+
+func (p *cachedProject) Save(l *todo.List) error {
+	return p.c.save(p.name, l)
+}
+
+func (p *cachedProject) LoadHistory(h *todo.History) error {
+	return p.c.loadHistory(p.name, h)
+}
+
+func (p *cachedProject) SaveHistory(h *todo.History) error {
+	return p.c.saveHistory(p.name, h)
+}
+
+func (c *cachedStorage) Load(l *todo.List) error {
+	return c.load(todo.DefaultProject, l)
+}
+
+func (c *cachedStorage) Save(l *todo.List) error {
+	return c.save(todo.DefaultProject, l)
+}
+
+func (c *cachedStorage) LoadHistory(h *todo.History) error {
+	return c.loadHistory(todo.DefaultProject, h)
+}
+
+func (c *cachedStorage) SaveHistory(h *todo.History) error {
+	return c.saveHistory(todo.DefaultProject, h)
+}
+
+func (c *cachedStorage) Project(name string) (todo.Storage, error) {
+	// The backend validates the name
+	if _, err := c.backend.Project(name); err != nil {
+		return nil, err
+	}
+
+	return &cachedProject{c: c, name: name}, nil
+}
+
+// Names of the stored projects and of the projects changed since,
+// without the emptied ones like the storages remove them
+func (c *cachedStorage) Projects() ([]string, error) {
+	names, err := c.backend.Projects()
+	if err != nil {
+		return nil, err
+	}
+
+	set := map[string]bool{}
+	for _, name := range names {
+		set[name] = true
+	}
+
+	c.mu.RLock()
+	for name, l := range c.lists {
+		set[name] = len(l.Items) > 0 || name == todo.DefaultProject
+	}
+	c.mu.RUnlock()
+
+	names = names[:0]
+	for name, ok := range set {
+		if ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Gives a copy of the cached list, reading it from the backend the first time
+func (c *cachedStorage) load(name string, l *todo.List) error {
+	c.mu.RLock()
+	cached, ok := c.lists[name]
+	c.mu.RUnlock()
+
+	if !ok {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		// Another request may have read it meanwhile
+		if cached, ok = c.lists[name]; !ok {
+			v, err := c.read(name, &cached)
+			if err != nil {
+				return err
+			}
+			c.lists[name] = cached
+			c.versions[name] = v
+		}
+	}
+
+	// Requests change their copy before saving it
+	*l = cached.Clone()
+	return nil
+}
+
+// Reads the list from the backend and returns its version
+func (c *cachedStorage) read(name string, l *todo.List) (version, error) {
+	s, err := c.backend.Project(name)
+	if err != nil {
+		return version{}, err
+	}
+
+	if lk, ok := s.(todo.Locker); ok {
+		if err := lk.Lock(); err != nil {
+			return version{}, err
+		}
+		defer lk.Unlock()
+	}
+
+	at := time.Now()
+	if err := s.Load(l); err != nil {
+		return version{}, err
+	}
+
+	return version{etag: etagOf(*l), at: at}, nil
+}
+
+// Gives the history waiting to be written or reads it from the backend.
+// Backends without history give an empty one.
+func (c *cachedStorage) loadHistory(name string, h *todo.History) error {
+	c.mu.RLock()
+	pending, ok := c.histories[name]
+	c.mu.RUnlock()
+
+	if ok {
+		// Recording appends to the entries
+		*h = *pending
+		h.Entries = append([]todo.Entry(nil), pending.Entries...)
+		return nil
+	}
+
+	s, err := c.backend.Project(name)
+	if err != nil {
+		return err
+	}
+
+	hs, ok := s.(todo.HistoryStorage)
+	if !ok {
+		return nil
+	}
+
+	if lk, ok := s.(todo.Locker); ok {
+		if err := lk.Lock(); err != nil {
+			return err
+		}
+		defer lk.Unlock()
+	}
+
+	return hs.LoadHistory(h)
+}
+
+// Keeps the history to write it with the list. Backends without history drop it.
+func (c *cachedStorage) saveHistory(name string, h *todo.History) error {
+	s, err := c.backend.Project(name)
+	if err != nil {
+		return err
+	}
+	if _, ok := s.(todo.HistoryStorage); !ok {
+		return nil
+	}
+
+	c.mu.Lock()
+	c.histories[name] = h
+	c.mu.Unlock()
+
+	return c.changed(name)
+}
+
+// Replaces the cached list and schedules the write
+func (c *cachedStorage) save(name string, l *todo.List) error {
+	c.mu.Lock()
+	c.lists[name] = l.Clone()
+	c.mu.Unlock()
+
+	return c.changed(name)
+}
+
+// Schedules the write of the project, or writes it now without delay
+func (c *cachedStorage) changed(name string) error {
+	c.mu.Lock()
+	c.dirty[name] = true
+	if c.delay > 0 {
+		c.schedule(c.delay)
+	}
+	c.mu.Unlock()
+
+	if c.delay > 0 {
+		return nil
+	}
+
+	return c.Flush()
+}
+
+// Flushes after the delay unless a flush is already scheduled. c.mu must be held.
+func (c *cachedStorage) schedule(delay time.Duration) {
+	if c.timer != nil {
+		return
+	}
+
+	c.timer = time.AfterFunc(delay, func() {
+		if err := c.Flush(); err != nil {
+			slog.Error("cannot write the todo lists", "error", err)
+		}
+	})
+}
+
+// Writes the changed lists to the backend. Lists that fail stay pending
+// and are written again by a retry or by the next Flush.
+// Retries wait longer after each failure, up to maxRetryDelay.
+func (c *cachedStorage) Flush() error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	pending := make(map[string]todo.List, len(c.dirty))
+	for name := range c.dirty {
+		pending[name] = c.lists[name]
+	}
+	histories := c.histories
+	c.dirty = map[string]bool{}
+	c.histories = map[string]*todo.History{}
+	c.mu.Unlock()
+
+	var errs []error
+	for name, l := range pending {
+		if err := c.write(name, &l, histories[name]); err != nil {
+			errs = append(errs, err)
+
+			c.mu.Lock()
+			c.dirty[name] = true
+			if h, ok := histories[name]; ok {
+				if _, saved := c.histories[name]; !saved {
+					c.histories[name] = h
+				}
+			}
+			c.mu.Unlock()
+		}
+	}
+
+	c.mu.Lock()
+	if len(errs) > 0 {
+		c.retry = min(max(2*c.retry, minRetryDelay), maxRetryDelay)
+		c.schedule(c.retry)
+	} else {
+		c.retry = 0
+	}
+	c.mu.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// Writes the list and the history unless it is nil. If the stored list
+// changed since the cache read or wrote it, the list is merged with it,
+// the cached list gets the changes too, and the stored history is kept,
+// as the entries of the server do not apply to the merged list.
+func (c *cachedStorage) write(name string, l *todo.List, h *todo.History) error {
+	s, err := c.backend.Project(name)
+	if err != nil {
+		return err
+	}
+
+	if lk, ok := s.(todo.Locker); ok {
+		if err := lk.Lock(); err != nil {
+			return err
+		}
+		defer lk.Unlock()
+	}
+
+	stored := todo.List{}
+	if err := s.Load(&stored); err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	last := c.versions[name]
+	c.mu.RUnlock()
+
+	merged := *l
+	changed := etagOf(stored) != last.etag
+	if changed {
+		var result todo.SyncResult
+		merged, _, result = todo.Reconcile(*l, stored, last.at)
+		slog.Warn("todo list changed by another process", "project", name,
+			"pulled", result.Pulled, "conflicts", len(result.Conflicts))
+	}
+
+	at := time.Now()
+	if err := s.Save(&merged); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.versions[name] = version{etag: etagOf(merged), at: at}
+	if changed {
+		// Requests may have changed the list while it was written
+		if c.dirty[name] {
+			c.lists[name], _, _ = todo.Reconcile(c.lists[name], merged, last.at)
+		} else {
+			c.lists[name] = merged
+		}
+	}
+	c.mu.Unlock()
+
+	if h == nil || changed {
+		return nil
+	}
+
+	return s.(todo.HistoryStorage).SaveHistory(h)
+}
+
+// Writes the pending changes
+func (c *cachedStorage) Close() error {
+	return c.Flush()
+}
+
+// Opens the storages of the server behind caches and closes them together
+type cachedStorages struct {
+	delay time.Duration
+	opts  []storage.Option
+
+	mu     sync.Mutex
+	caches []*cachedStorage
+}
+
+// Opens the storage of the URI behind a cache
+func (cs *cachedStorages) open(uri string) (todo.ProjectStorage, error) {
+	backend, err := storage.New(uri, cs.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newCachedStorage(backend, cs.delay)
+
+	cs.mu.Lock()
+	cs.caches = append(cs.caches, c)
+	cs.mu.Unlock()
+
+	return c, nil
+}
+
+// Writes the pending changes of all storages
+func (cs *cachedStorages) Close() error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	var errs []error
+	for _, c := range cs.caches {
+		errs = append(errs, c.Close())
+	}
+
+	return errors.Join(errs...)
+}
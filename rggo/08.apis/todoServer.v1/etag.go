@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/sha256"         // To hash the content of the entities
+	"encoding/hex"          // To format the entity tags
+	"encoding/json"         // To hash the JSON encoding of the entities
+	"net/http"              // To deal with HTTP requests and responses
+	"rggo/interacting/todo" // todo application
+	"strings"               // To parse the conditional headers
+)
+
+// Entity tag of the list. It changes with every change of the content of the list.
+// Times are hashed in UTC, as storages may load them in another location.
+func etagOf(list todo.List) string {
+	l := list.Clone()
+	for k := range l.Items {
+		t := &l.Items[k]
+		t.CreatedAt = t.CreatedAt.UTC()
+		t.CompletedAt = t.CompletedAt.UTC()
+		t.UpdatedAt = t.UpdatedAt.UTC()
+		t.Due = t.Due.UTC()
+	}
+
+	js, err := json.Marshal(l)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(js)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// Reports whether the If-Match or If-None-Match header lists the tag.
+// Weak tags like W/"..." only match for If-None-Match.
+func etagMatch(header, tag string, weak bool) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if weak {
+			t = strings.TrimPrefix(t, "W/")
+		}
+		if t == "*" || t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sets the ETag header and checks the conditional headers of the request.
+// GET requests with a matching If-None-Match get 304, and other requests with
+// an If-Match that does not match get 412, as the entity changed since the client read it.
+// Returns false when the request must not go on.
+func checkPreconditions(w http.ResponseWriter, r *http.Request, tag string,
+	reply func(w http.ResponseWriter, r *http.Request, status int, message string)) bool {
+
+	w.Header().Set("ETag", tag)
+
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		if h := r.Header.Get("If-None-Match"); h != "" && etagMatch(h, tag, true) {
+			w.WriteHeader(http.StatusNotModified)
+			return false
+		}
+		return true
+	}
+
+	if h := r.Header.Get("If-Match"); h != "" && !etagMatch(h, tag, false) {
+		reply(w, r, http.StatusPreconditionFailed, "Changed since version "+h+", now "+tag)
+		return false
+	}
+
+	return true
+}
+
+// Entity tag of the item with the ID, which must be in the list
+func itemETag(list *todo.List, id int) string {
+	idx, _ := list.Index(id)
+	return etagOf(todo.List{Items: list.Items[idx : idx+1]})
+}
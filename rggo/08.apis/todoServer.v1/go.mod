@@ -1,7 +1,17 @@
 module rggo/apis/todoServer
 
-go 1.21.4
+go 1.22
 
 require rggo/interacting/todo v0.0.0
 
+require (
+	github.com/mattn/go-sqlite3 v1.14.22 // indirect
+	golang.org/x/crypto v0.17.0 // indirect
+	golang.org/x/sys v0.15.0 // indirect
+)
+
 replace rggo/interacting/todo => ../../02.interacting/05.todo
+
+replace rggo/distributing/notify => ../../11.distributing/notify
+
+replace rggo/interactiveTools/pomo => ../../11.distributing/pomo
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"crypto/sha256"         // To check the checksums naming the blobs
+	"encoding/hex"          // To decode the checksums naming the blobs
 	"encoding/json"         // To work with JSON data
 	"errors"                // To define and handle errors
 	"fmt"                   // To print formatted output
+	"io"                    // To store uploaded attachments while reading them
 	"net/http"              // To deal with HTTP requests and responses
+	"os"                    // To read and write the blobs of attachments
+	"path/filepath"         // To build the paths of the blobs
 	"rggo/interacting/todo" // todo application
 	"strconv"               // To convert strings to integer numbers
-	"sync"                  // To use the type sync.Mutex to prevent racing conditions when accessing to-do file
+	"strings"               // To split the project name from the path
+	"sync"                  // To use the type sync.RWMutex to prevent racing conditions when accessing to-do storage
 )
 
 var (
@@ -15,6 +21,9 @@ var (
 	ErrInvalidData = errors.New("invalid data")
 )
 
+// Largest attachment accepted by uploads in bytes
+var maxUploadSize int64 = 32 << 20
+
 func rootHandler(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
 		replyError(w, r, http.StatusNotFound, "")
@@ -25,23 +34,38 @@ func rootHandler(w http.ResponseWriter, r *http.Request) {
 	replyTextContent(w, r, http.StatusOK, content)
 }
 
-func todoRouter(todoFile string, l sync.Locker) http.HandlerFunc {
+func todoRouter(store todo.Storage, l *sync.RWMutex, blobs string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		list := &todo.List{}
 
-		l.Lock()
-		defer l.Unlock()
-		if err := list.Get(todoFile); err != nil {
+		defer lockFor(r, l)()
+
+		// Other processes may share the storage
+		if lk, ok := store.(todo.Locker); ok {
+			if err := lk.Lock(); err != nil {
+				replyError(w, r, http.StatusServiceUnavailable, err.Error())
+				return
+			}
+			defer lk.Unlock()
+		}
+
+		if err := store.Load(list); err != nil {
 			replyError(w, r, http.StatusInternalServerError, err.Error())
 			return
 		}
 
 		if r.URL.Path == "" {
+			if !checkPreconditions(w, r, etagOf(*list), replyError) {
+				return
+			}
+
 			switch r.Method {
 			case http.MethodGet:
 				getAllHandler(w, r, list)
 			case http.MethodPost:
-				addHandler(w, r, list, todoFile)
+				addHandler(w, r, list, heldList{store, list})
+			case http.MethodPut:
+				replaceHandler(w, r, heldList{store, list})
 			default:
 				message := "Method not supported"
 				replyError(w, r, http.StatusMethodNotAllowed, message)
@@ -49,7 +73,8 @@ func todoRouter(todoFile string, l sync.Locker) http.HandlerFunc {
 			return
 		}
 
-		id, err := validateID(r.URL.Path, list)
+		idPath, sub, hasSub := strings.Cut(r.URL.Path, "/")
+		id, err := validateID(idPath, list)
 		if err != nil {
 			if errors.Is(err, ErrNotFound) {
 				replyError(w, r, http.StatusNotFound, err.Error())
@@ -58,13 +83,25 @@ func todoRouter(todoFile string, l sync.Locker) http.HandlerFunc {
 			replyError(w, r, http.StatusBadRequest, err.Error())
 			return
 		}
+
+		if hasSub {
+			attachmentsRouter(w, r, list, id, sub, heldList{store, list}, blobs)
+			return
+		}
+
+		if !checkPreconditions(w, r, itemETag(list, id), replyError) {
+			return
+		}
+
 		switch r.Method {
 		case http.MethodGet:
 			getOneHandler(w, r, list, id)
 		case http.MethodDelete:
-			deleteHandler(w, r, list, id, todoFile)
+			deleteHandler(w, r, id, heldList{store, list})
 		case http.MethodPatch:
-			patchHanlder(w, r, list, id, todoFile)
+			patchHanlder(w, r, list, id, heldList{store, list})
+		case http.MethodPut:
+			editHandler(w, r, list, id, heldList{store, list})
 		default:
 			message := "Method not supported"
 			replyError(w, r, http.StatusMethodNotAllowed, message)
@@ -72,51 +109,221 @@ func todoRouter(todoFile string, l sync.Locker) http.HandlerFunc {
 	}
 }
 
+// Locks l for reading for GET and HEAD requests, which run at the same time,
+// and for writing otherwise. Returns the function unlocking it.
+func lockFor(r *http.Request, l *sync.RWMutex) func() {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		l.RLock()
+		return l.RUnlock
+	}
+
+	l.Lock()
+	return l.Unlock
+}
+
+// Storage of a list the router has already loaded and locked. Handlers change
+// the list with todo.Update, which records the change when the storage keeps
+// the history, and the list is replaced with the saved one.
+type heldList struct {
+	store todo.Storage
+	list  *todo.List
+}
+
+func (s heldList) Load(l *todo.List) error {
+	*l = s.list.Clone()
+	return nil
+}
+
+func (s heldList) Save(l *todo.List) error {
+	if err := s.store.Save(l); err != nil {
+		return err
+	}
+
+	*s.list = *l
+	return nil
+}
+
+func (s heldList) LoadHistory(h *todo.History) error {
+	if hs, ok := s.store.(todo.HistoryStorage); ok {
+		return hs.LoadHistory(h)
+	}
+
+	return nil
+}
+
+func (s heldList) SaveHistory(h *todo.History) error {
+	if hs, ok := s.store.(todo.HistoryStorage); ok {
+		return hs.SaveHistory(h)
+	}
+
+	return nil
+}
+
+// Lists the projects of the storage with the number of open and all items
+func projectsHandler(store todo.ProjectStorage, l *sync.RWMutex) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			message := "Method not supported"
+			replyError(w, r, http.StatusMethodNotAllowed, message)
+			return
+		}
+
+		l.RLock()
+		defer l.RUnlock()
+
+		names, err := store.Projects()
+		if err != nil {
+			replyError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		resp := &projectsResponse{Results: []projectSummary{}}
+		for _, name := range names {
+			ps, err := store.Project(name)
+			if err != nil {
+				replyError(w, r, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+			list := todo.List{}
+			if err := ps.Load(&list); err != nil {
+				replyError(w, r, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+			resp.Results = append(resp.Results, projectSummary{
+				Name:  name,
+				Open:  len(list.Select(todo.Filter{Status: todo.StatusOpen}).Items),
+				Total: len(list.Items),
+			})
+		}
+
+		replyJSONContent(w, r, http.StatusOK, resp)
+	}
+}
+
+// Serves {name}/todo and {name}/todo/{id} with the list of the named project
+func projectRouter(store todo.ProjectStorage, l *sync.RWMutex, blobs string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, rest, _ := strings.Cut(r.URL.Path, "/")
+		if rest != "todo" && !strings.HasPrefix(rest, "todo/") {
+			replyError(w, r, http.StatusNotFound, "")
+			return
+		}
+
+		ps, err := store.Project(name)
+		if err != nil {
+			replyError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		prefix := name + "/todo"
+		if strings.HasPrefix(rest, "todo/") {
+			prefix += "/"
+		}
+		http.StripPrefix(prefix, todoRouter(ps, l, blobs)).ServeHTTP(w, r)
+	}
+}
+
+// Sends the page of the items selected by the query params
 func getAllHandler(w http.ResponseWriter, r *http.Request, list *todo.List) {
+	lq, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		replyError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, total := lq.page(list)
 	resp := &todoResponse{
-		Results: *list,
+		Results:      page,
+		TotalResults: total,
+		NextID:       list.NextID,
 	}
+	resp.Next, resp.Prev = lq.links(r.RequestURI, total)
+
 	replyJSONContent(w, r, http.StatusOK, resp)
 }
 
 func getOneHandler(w http.ResponseWriter, r *http.Request, list *todo.List, id int) {
+	idx, err := list.Index(id)
+	if err != nil {
+		replyError(w, r, http.StatusNotFound, err.Error())
+		return
+	}
+
 	resp := &todoResponse{
-		Results: (*list)[id-1 : id],
+		Results:      todo.List{Items: list.Items[idx : idx+1]},
+		TotalResults: 1,
 	}
 	replyJSONContent(w, r, http.StatusOK, resp)
 }
 
-func deleteHandler(
-	w http.ResponseWriter, r *http.Request, list *todo.List, id int, todoFile string) {
-
-	list.Delete(id)
-	if err := list.Save(todoFile); err != nil {
+func deleteHandler(w http.ResponseWriter, r *http.Request, id int, store todo.Storage) {
+	err := todo.Update(store, func(l *todo.List) error {
+		return l.Delete(id)
+	})
+	if err != nil {
 		replyError(w, r, http.StatusInternalServerError, err.Error())
 		return
 	}
+
+	// The item is gone, so it has no version
+	w.Header().Del("ETag")
 	replyTextContent(w, r, http.StatusNoContent, "")
 }
 
 func patchHanlder(
-	w http.ResponseWriter, r *http.Request, list *todo.List, id int, todoFile string) {
+	w http.ResponseWriter, r *http.Request, list *todo.List, id int, store todo.Storage) {
 
 	q := r.URL.Query()
-	if _, ok := q["complete"]; !ok {
-		message := "Missing query param 'complete'"
+	var change func(l *todo.List) error
+	switch {
+	case q.Has("complete") && q.Has("force"):
+		change = func(l *todo.List) error { return l.ForceComplete(id) }
+	case q.Has("complete"):
+		change = func(l *todo.List) error { return l.Complete(id) }
+	case q.Has("reopen"):
+		change = func(l *todo.List) error { return l.Reopen(id) }
+	case q.Has("move"):
+		pos, err := strconv.Atoi(q.Get("move"))
+		if err != nil {
+			message := fmt.Sprintf("Invalid position: %s", err)
+			replyError(w, r, http.StatusBadRequest, message)
+			return
+		}
+		change = func(l *todo.List) error { return l.Move(id, pos) }
+	default:
+		message := "Missing query param 'complete', 'reopen' or 'move'"
 		replyError(w, r, http.StatusBadRequest, message)
 		return
 	}
 
-	list.Complete(id)
-	if err := list.Save(todoFile); err != nil {
+	// Invalid changes are told from the storage errors
+	var changeErr error
+	err := todo.Update(store, func(l *todo.List) error {
+		changeErr = change(l)
+		return changeErr
+	})
+	if errors.Is(changeErr, todo.ErrOpenSubtasks) {
+		replyError(w, r, http.StatusConflict, changeErr.Error())
+		return
+	}
+	if changeErr != nil {
+		replyError(w, r, http.StatusBadRequest, changeErr.Error())
+		return
+	}
+	if err != nil {
 		replyError(w, r, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	w.Header().Set("ETag", itemETag(list, id))
 	replyTextContent(w, r, http.StatusNoContent, "")
 }
 
-func addHandler(w http.ResponseWriter, r *http.Request, list *todo.List, todoFile string) {
+func editHandler(
+	w http.ResponseWriter, r *http.Request, list *todo.List, id int, store todo.Storage) {
+
 	item := struct {
 		Task string `json:"task"`
 	}{}
@@ -127,14 +334,253 @@ func addHandler(w http.ResponseWriter, r *http.Request, list *todo.List, todoFil
 		return
 	}
 
-	list.Add(item.Task)
-	if err := list.Save(todoFile); err != nil {
+	if item.Task == "" {
+		replyError(w, r, http.StatusBadRequest, "Missing task name")
+		return
+	}
+
+	err := todo.Update(store, func(l *todo.List) error {
+		return l.Edit(id, item.Task)
+	})
+	if err != nil {
+		replyError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", itemETag(list, id))
+	replyTextContent(w, r, http.StatusNoContent, "")
+}
+
+func addHandler(w http.ResponseWriter, r *http.Request, list *todo.List, store todo.Storage) {
+	item := struct {
+		Task      string `json:"task"`
+		Parent    int    `json:"parent"`
+		BlockedBy []int  `json:"blocked_by"`
+	}{}
+
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		message := fmt.Sprintf("Invalid JSON: %s", err)
+		replyError(w, r, http.StatusBadRequest, message)
+		return
+	}
+
+	var changeErr error
+	err := todo.Update(store, func(l *todo.List) error {
+		l.Add(item.Task)
+		id := l.Items[len(l.Items)-1].ID
+		if item.Parent != 0 {
+			if changeErr = l.SetParent(id, item.Parent); changeErr != nil {
+				return changeErr
+			}
+		}
+		for _, b := range item.BlockedBy {
+			if changeErr = l.Block(id, b); changeErr != nil {
+				return changeErr
+			}
+		}
+		return nil
+	})
+	if changeErr != nil {
+		replyError(w, r, http.StatusBadRequest, changeErr.Error())
+		return
+	}
+	if err != nil {
+		replyError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", etagOf(*list))
+	replyTextContent(w, r, http.StatusCreated, "")
+}
+
+// Serves attachments and attachments/{n} of the item. The content of the
+// attachments is kept in the blob directory in files named by their SHA-256.
+func attachmentsRouter(w http.ResponseWriter, r *http.Request,
+	list *todo.List, id int, path string, store todo.Storage, blobs string) {
+
+	idx, _ := list.Index(id)
+	attachments := list.Items[idx].Attachments
+
+	if path == "attachments" {
+		switch r.Method {
+		case http.MethodGet:
+			resp := &attachmentsResponse{Results: append([]todo.Attachment{}, attachments...)}
+			replyJSONContent(w, r, http.StatusOK, resp)
+		case http.MethodPost:
+			uploadHandler(w, r, list, id, store, blobs)
+		default:
+			message := "Method not supported"
+			replyError(w, r, http.StatusMethodNotAllowed, message)
+		}
+		return
+	}
+
+	if !strings.HasPrefix(path, "attachments/") {
+		replyError(w, r, http.StatusNotFound, "")
+		return
+	}
+	if r.Method != http.MethodGet {
+		message := "Method not supported"
+		replyError(w, r, http.StatusMethodNotAllowed, message)
+		return
+	}
+
+	nPath := strings.TrimPrefix(path, "attachments/")
+	n, err := strconv.Atoi(nPath)
+	if err != nil || n < 1 || n > len(attachments) {
+		message := fmt.Sprintf("Attachment %s of item %d not found", nPath, id)
+		replyError(w, r, http.StatusNotFound, message)
+		return
+	}
+
+	contentHandler(w, r, attachments[n-1], blobs)
+}
+
+// Sends the content of the attachment from the blob directory
+func contentHandler(w http.ResponseWriter, r *http.Request, a todo.Attachment, blobs string) {
+	if blobs == "" {
+		replyError(w, r, http.StatusNotFound, "No blob directory")
+		return
+	}
+
+	// The checksum comes from the list, so it must not lead out of the directory
+	if _, err := hex.DecodeString(a.SHA256); err != nil || len(a.SHA256) != sha256.Size*2 {
+		replyError(w, r, http.StatusNotFound, "Invalid checksum")
+		return
+	}
+
+	f, err := os.Open(filepath.Join(blobs, a.SHA256))
+	if errors.Is(err, os.ErrNotExist) {
+		replyError(w, r, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		replyError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		replyError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	http.ServeContent(w, r, filepath.Base(a.Path), info.ModTime(), f)
+}
+
+// Stores the request body in the blob directory and attaches it to the item
+// with the name given in the query param 'name'
+func uploadHandler(
+	w http.ResponseWriter, r *http.Request, list *todo.List, id int, store todo.Storage, blobs string) {
+
+	if blobs == "" {
+		replyError(w, r, http.StatusNotFound, "No blob directory")
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		replyError(w, r, http.StatusBadRequest, "Missing query param 'name'")
+		return
+	}
+
+	tmp, err := os.CreateTemp(blobs, "upload")
+	if err != nil {
+		replyError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	body := &bodyReader{r: http.MaxBytesReader(w, r.Body, maxUploadSize)}
+	a, err := todo.ReadAttachment(name, io.TeeReader(body, tmp))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(body.err, &tooLarge):
+		message := fmt.Sprintf("Attachment larger than %d bytes", tooLarge.Limit)
+		replyError(w, r, http.StatusRequestEntityTooLarge, message)
+		return
+	case body.err != nil:
+		message := fmt.Sprintf("Invalid upload: %s", body.err)
+		replyError(w, r, http.StatusBadRequest, message)
+		return
+	case err != nil:
+		replyError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(blobs, a.SHA256)); err != nil {
 		replyError(w, r, http.StatusInternalServerError, err.Error())
 		return
 	}
+
+	err = todo.Update(store, func(l *todo.List) error {
+		return l.Attach(id, a)
+	})
+	if err != nil {
+		replyError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	replyTextContent(w, r, http.StatusCreated, "")
 }
 
+// Reader of a request body keeping its error,
+// so it can be told from the errors of writing the upload
+type bodyReader struct {
+	r   io.Reader
+	err error
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+
+	return n, err
+}
+
+// Replaces the whole list, as a client does after syncing its own list
+func replaceHandler(w http.ResponseWriter, r *http.Request, store todo.Storage) {
+	list := todo.List{}
+	if err := json.NewDecoder(r.Body).Decode(&list); err != nil {
+		message := fmt.Sprintf("Invalid JSON: %s", err)
+		replyError(w, r, http.StatusBadRequest, message)
+		return
+	}
+
+	if err := list.Validate(); err != nil {
+		replyError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := todo.Update(store, func(l *todo.List) error {
+		// IDs given by the server are not given again,
+		// even if the client did not see them
+		next := max(l.NextID, list.NextID)
+		for _, t := range l.Items {
+			next = max(next, t.ID+1)
+		}
+
+		*l = list
+		l.NextID = next
+		list = *l
+		return nil
+	})
+	if err != nil {
+		replyError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", etagOf(list))
+	replyTextContent(w, r, http.StatusNoContent, "")
+}
+
 func validateID(path string, list *todo.List) (int, error) {
 	id, err := strconv.Atoi(path)
 	if err != nil {
@@ -143,7 +589,7 @@ func validateID(path string, list *todo.List) (int, error) {
 	if id < 1 {
 		return 0, fmt.Errorf("%w: Invalid ID: Less than one", ErrInvalidData)
 	}
-	if id > len(*list) {
+	if _, err := list.Index(id); err != nil {
 		return id, fmt.Errorf("%w: ID %d not found", ErrNotFound, id)
 	}
 
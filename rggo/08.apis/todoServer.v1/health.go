@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"    // To serve the checks
+	"sync/atomic" // To flip the readiness from other goroutines
+)
+
+// Serves the liveness and readiness checks next to the API.
+// The server is live as long as it answers; it is ready once the store
+// is loaded and until it starts shutting down.
+type health struct {
+	ready atomic.Bool
+}
+
+func (h *health) setReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+// Serves /healthz and /readyz without authentication, everything else by next
+func (h *health) handler(next http.Handler) http.Handler {
+	m := http.NewServeMux()
+	m.Handle("/", next)
+
+	m.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		replyTextContent(w, r, http.StatusOK, "ok\n")
+	})
+
+	m.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !h.ready.Load() {
+			replyTextContent(w, r, http.StatusServiceUnavailable, "not ready\n")
+			return
+		}
+		replyTextContent(w, r, http.StatusOK, "ok\n")
+	})
+
+	return m
+}
@@ -1,27 +1,137 @@
 package main
 
 import (
-	"flag"     // To handle command-line options
-	"fmt"      // To format output
-	"net/http" // To handle HTTP connections
-	"os"       // For operating system-related functions
-	"time"     // To define variables based on time to handle timeouts
+	"context"                       // To limit the time of the shutdown
+	"errors"                        // To tell the shutdown from other errors
+	"flag"                          // To handle command-line options
+	"fmt"                           // To format output
+	"log/slog"                      // To log requests as JSON lines
+	"net/http"                      // To handle HTTP connections
+	"os"                            // For operating system-related functions
+	"os/signal"                     // To shut down on interrupt
+	"rggo/interacting/todo"         // To read the encryption key
+	"rggo/interacting/todo/storage" // To choose the to-do storage backend
+	"syscall"                       // To shut down on SIGTERM
+	"time"                          // To define variables based on time to handle timeouts
 )
 
 func main() {
 	host := flag.String("h", "localhost", "Server host")
 	port := flag.Int("p", 8080, "Server port")
-	todoFile := flag.String("f", "todoServer.json", "todo JSON file")
+	todoFile := flag.String("f", "todoServer.json", "todo JSON file or storage URI like sqlite:///path/todo.db")
+	blobs := flag.String("blobs", "", "Directory with the content of the attachments")
+	keyFile := flag.String("key-file", "", "File with the passphrase encrypting the todo JSON file. Plain files are encrypted first with todo -encrypt")
+	authFile := flag.String("auth", "", "JSON file with the API keys and the token secret. Each user gets their own lists")
+	issueToken := flag.String("issue-token", "", "Print a bearer token for the user signed with the -auth secret and exit")
+	tokenTTL := flag.Duration("token-ttl", 30*24*time.Hour, "How long the token of -issue-token is valid")
+	writeDelay := flag.Duration("write-delay", time.Second, "How long changes are kept in memory before they are written. 0 writes every change at once")
+	drainTimeout := flag.Duration("drain-timeout", 10*time.Second, "How long the requests in progress may take to finish on shutdown")
 	flag.Parse()
 
+	var auth *authConfig
+	if *authFile != "" {
+		var err error
+		if auth, err = loadAuthConfig(*authFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	if *issueToken != "" {
+		if auth == nil {
+			fmt.Fprintln(os.Stderr, "Missing -auth file with the token secret")
+			os.Exit(1)
+		}
+		token, err := auth.issueToken(*issueToken, time.Now().Add(*tokenTTL))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(token)
+		return
+	}
+
+	if *blobs != "" {
+		if err := os.MkdirAll(*blobs, 0755); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	opts := []storage.Option{}
+	if *keyFile != "" {
+		c, err := todo.NewCipherFromFile(*keyFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		opts = append(opts, storage.WithCipher(c))
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	slog.SetDefault(logger)
+
+	// Lists are kept in memory and written on shutdown at the latest
+	stores := &cachedStorages{delay: *writeDelay, opts: opts}
+	h := &health{}
+
+	var handler http.Handler
+	if auth != nil {
+		// The lists of each user are loaded on their first request
+		handler = newAuthHandler(auth, userHandlers(*todoFile, *blobs, stores.open))
+		h.setReady(true)
+	} else {
+		store, err := stores.open(*todoFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		handler = newMux(store, *blobs)
+
+		// Ready once the list is in memory
+		go func() {
+			if err := store.Load(&todo.List{}); err != nil {
+				logger.Error("cannot load the todo list", "error", err)
+				return
+			}
+			h.setReady(true)
+		}()
+	}
+
 	s := &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", *host, *port),
-		Handler:      newMux(*todoFile),
+		Handler:      accessLog(logger, h.handler(handler)),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
 
-	if err := s.ListenAndServe(); err != nil {
+	// Finish the requests in progress when interrupted
+	shutdown := make(chan error, 1)
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+
+		// Stop getting new traffic while the requests in progress finish
+		h.setReady(false)
+		logger.Info("shutting down", "drain_timeout", drainTimeout.String())
+
+		ctx, cancel := context.WithTimeout(context.Background(), *drainTimeout)
+		defer cancel()
+		shutdown <- s.Shutdown(ctx)
+	}()
+
+	logger.Info("listening", "addr", s.Addr)
+	if err := s.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	err := <-shutdown
+	if closeErr := stores.Close(); closeErr != nil {
+		err = errors.Join(err, closeErr)
+	}
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
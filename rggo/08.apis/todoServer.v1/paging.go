@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"                   // To format error messages
+	"net/url"               // To compose the links to other pages
+	"rggo/interacting/todo" // todo application
+	"strconv"               // To parse the numeric query params
+)
+
+// Items of a list endpoint selected by the query params:
+//
+//	limit=N          - at most N items; 0 or missing gives all of them
+//	offset=N         - skip the first N matching items
+//	done=true|false  - only completed or open items
+//	q=text           - items whose task contains the text, ignoring case
+//	created_after=T  - items created at or after T, a YYYY-MM-DD date or RFC 3339 time
+//	sort=order       - created, completed or alpha; missing keeps the list order
+type listQuery struct {
+	filter todo.Filter
+	limit  int
+	offset int
+}
+
+func parseListQuery(q url.Values) (listQuery, error) {
+	lq := listQuery{}
+
+	var err error
+	if lq.limit, err = nonNegative(q, "limit"); err != nil {
+		return lq, err
+	}
+	if lq.offset, err = nonNegative(q, "offset"); err != nil {
+		return lq, err
+	}
+
+	if q.Has("done") {
+		done, err := strconv.ParseBool(q.Get("done"))
+		if err != nil {
+			return lq, fmt.Errorf("%w: Invalid done: %q", ErrInvalidData, q.Get("done"))
+		}
+		lq.filter.Status = todo.StatusOpen
+		if done {
+			lq.filter.Status = todo.StatusDone
+		}
+	}
+
+	lq.filter.Search = q.Get("q")
+
+	if q.Get("created_after") != "" {
+		if lq.filter.CreatedSince, err = parseDue(q.Get("created_after")); err != nil {
+			return lq, fmt.Errorf("%w: Invalid created_after: %q", ErrInvalidData, q.Get("created_after"))
+		}
+	}
+
+	if lq.filter.Sort, err = todo.ParseSort(q.Get("sort")); err != nil {
+		return lq, fmt.Errorf("%w: %s", ErrInvalidData, err)
+	}
+
+	return lq, nil
+}
+
+func nonNegative(q url.Values, name string) (int, error) {
+	if !q.Has(name) {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(q.Get(name))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w: Invalid %s: %q", ErrInvalidData, name, q.Get(name))
+	}
+
+	return n, nil
+}
+
+// Returns the page of the items matching the query and the number of all matching items
+func (lq listQuery) page(list *todo.List) (todo.List, int) {
+	selected := list.Select(lq.filter)
+	total := len(selected.Items)
+
+	start := min(lq.offset, total)
+	end := total
+	if lq.limit > 0 {
+		end = min(start+lq.limit, total)
+	}
+
+	return todo.List{Items: selected.Items[start:end]}, total
+}
+
+// Links to the next and the previous pages of the request, keeping its other
+// query params. They are empty when there is no such page.
+func (lq listQuery) links(requestURI string, total int) (next, prev string) {
+	if lq.limit == 0 {
+		return "", ""
+	}
+
+	u, err := url.ParseRequestURI(requestURI)
+	if err != nil {
+		return "", ""
+	}
+
+	link := func(offset int) string {
+		q := u.Query()
+		q.Set("limit", strconv.Itoa(lq.limit))
+		q.Set("offset", strconv.Itoa(offset))
+		return u.Path + "?" + q.Encode()
+	}
+
+	if lq.offset+lq.limit < total {
+		next = link(lq.offset + lq.limit)
+	}
+	if lq.offset > 0 {
+		prev = link(max(lq.offset-lq.limit, 0))
+	}
+
+	return next, prev
+}
@@ -1,10 +1,11 @@
 package main
 
 import (
-	"encoding/json" // To convert data to json
-	"log"           // To log errors
-	"net/http"      // To respond to HTTP requests
-	"sync"          // To use the type sync.Mutex
+	"encoding/json"         // To convert data to json
+	"log/slog"              // To log errors
+	"net/http"              // To respond to HTTP requests
+	"rggo/interacting/todo" // To-do application
+	"sync"                  // To use the type sync.RWMutex
 )
 
 const (
@@ -13,16 +14,23 @@ const (
 	ContentApplicationJson = "application/json"
 )
 
-func newMux(todoFile string) http.Handler {
+// Serves the lists of the storage. The content of attachments is kept
+// in the blobs directory; without one only their metadata is served.
+func newMux(store todo.ProjectStorage, blobs string) http.Handler {
 	m := http.NewServeMux()
-	mu := &sync.Mutex{}
+	mu := &sync.RWMutex{}
 
 	m.HandleFunc("/", rootHandler)
 
-	t := todoRouter(todoFile, mu)
+	t := todoRouter(store, mu, blobs)
 	m.Handle("/todo", http.StripPrefix("/todo", t))
 	m.Handle("/todo/", http.StripPrefix("/todo/", t))
 
+	m.HandleFunc("/projects", projectsHandler(store, mu))
+	m.Handle("/projects/", http.StripPrefix("/projects/", projectRouter(store, mu, blobs)))
+
+	registerV2(m, store, mu)
+
 	return m
 }
 
@@ -32,10 +40,11 @@ func replyTextContent(w http.ResponseWriter, r *http.Request, status int, conten
 	w.Write([]byte(content))
 }
 
-func replyJSONContent(w http.ResponseWriter, r *http.Request, status int, resp *todoResponse) {
+func replyJSONContent(w http.ResponseWriter, r *http.Request, status int, resp json.Marshaler) {
 	body, err := json.Marshal(resp)
 	if err != nil {
 		replyError(w, r, http.StatusInternalServerError, err.Error())
+		return
 	}
 
 	w.Header().Set(ContentType, ContentApplicationJson)
@@ -44,6 +53,26 @@ func replyJSONContent(w http.ResponseWriter, r *http.Request, status int, resp *
 }
 
 func replyError(w http.ResponseWriter, r *http.Request, status int, message string) {
-	log.Printf("%s %s: Error: %d %s", r.URL, r.Method, status, message)
+	logError(r, status, message)
 	http.Error(w, http.StatusText(status), status)
 }
+
+// Replies with the error envelope of the v2 API
+func replyJSONError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	logError(r, status, message)
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	replyJSONContent(w, r, status, &errorResponse{Status: status, Message: message})
+}
+
+// Logs the error with the request it replies to
+func logError(r *http.Request, status int, message string) {
+	slog.ErrorContext(r.Context(), "request failed",
+		"method", r.Method,
+		"uri", r.RequestURI,
+		"status", status,
+		"error", message,
+		"request_id", requestID(r.Context()),
+	)
+}
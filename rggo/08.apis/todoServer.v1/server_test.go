@@ -1,17 +1,25 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io" // To read the response body
 	"log"
+	"log/slog"
+	"net"
 	"net/http"          // To deal with HTTP requests
 	"net/http/httptest" // Provides HTTP testing utilities (test HTTP server)
 	"os"
 	"rggo/interacting/todo"
+	"rggo/interacting/todo/storage"
+	"sort"
 	"strings" // To compare strings
+	"sync"
 	"testing" // Provides testing utilities
+	"time"
 )
 
 func TestMain(m *testing.M) {
@@ -29,7 +37,7 @@ func setupAPI(t *testing.T) (string, func()) {
 	fmt.Printf("Using temporary to-do file %q\n", tempTodoFile.Name())
 
 	// Create the new test server
-	ts := httptest.NewServer(newMux(tempTodoFile.Name()))
+	ts := httptest.NewServer(newMux(storage.NewJSONFile(tempTodoFile.Name()), t.TempDir()))
 	fmt.Printf("Using test server with url: %q\n", ts.URL)
 
 	// Adding a couple of items for testing
@@ -56,6 +64,7 @@ func setupAPI(t *testing.T) (string, func()) {
 	return ts.URL, func() {
 		ts.Close()
 		os.Remove(tempTodoFile.Name())
+		os.Remove(tempTodoFile.Name() + ".lock")
 	}
 }
 
@@ -137,8 +146,8 @@ func TestGet(t *testing.T) {
 					if resp.TotalResults != tc.expItems {
 						t.Errorf("Expected %d items, got %d.", tc.expItems, resp.TotalResults)
 					}
-					if resp.Results[0].Task != tc.expContent {
-						t.Errorf("Expected %q, got %q.", tc.expContent, resp.Results[0].Task)
+					if resp.Results.Items[0].Task != tc.expContent {
+						t.Errorf("Expected %q, got %q.", tc.expContent, resp.Results.Items[0].Task)
 					}
 				case strings.Contains(r.Header.Get(ContentType), ContentTextPlain):
 					if body, err = io.ReadAll(r.Body); err != nil {
@@ -155,6 +164,111 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestGetQuery(t *testing.T) {
+	url, cleanup := setupAPI(t)
+	defer cleanup()
+
+	// Items 1 to 5, with items 3 and 4 completed
+	for _, task := range []string{"Another task.", "Task number 4.", "Task number 5."} {
+		body := strings.NewReader(fmt.Sprintf(`{"task": %q}`, task))
+		r, err := http.Post(url+"/todo", ContentApplicationJson, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Body.Close()
+	}
+	for _, id := range []int{3, 4} {
+		req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%s/todo/%d?complete", url, id), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Body.Close()
+	}
+
+	testCases := []struct {
+		name     string
+		query    string
+		expCode  int
+		expIDs   []int
+		expTotal int
+		expNext  string
+		expPrev  string
+	}{
+		{name: "All", query: "", expCode: http.StatusOK, expIDs: []int{1, 2, 3, 4, 5}, expTotal: 5},
+		{name: "FirstPage", query: "?limit=2", expCode: http.StatusOK, expIDs: []int{1, 2}, expTotal: 5,
+			expNext: "/todo?limit=2&offset=2"},
+		{name: "MiddlePage", query: "?limit=2&offset=2", expCode: http.StatusOK, expIDs: []int{3, 4}, expTotal: 5,
+			expNext: "/todo?limit=2&offset=4", expPrev: "/todo?limit=2&offset=0"},
+		{name: "LastPage", query: "?limit=2&offset=4", expCode: http.StatusOK, expIDs: []int{5}, expTotal: 5,
+			expPrev: "/todo?limit=2&offset=2"},
+		{name: "PastTheEnd", query: "?limit=2&offset=10", expCode: http.StatusOK, expIDs: []int{}, expTotal: 5,
+			expPrev: "/todo?limit=2&offset=8"},
+		{name: "Done", query: "?done=true", expCode: http.StatusOK, expIDs: []int{3, 4}, expTotal: 2},
+		{name: "OpenPage", query: "?done=false&limit=1", expCode: http.StatusOK, expIDs: []int{1}, expTotal: 3,
+			expNext: "/todo?done=false&limit=1&offset=1"},
+		{name: "Search", query: "?q=NUMBER", expCode: http.StatusOK, expIDs: []int{1, 2, 4, 5}, expTotal: 4},
+		{name: "CreatedAfter", query: "?created_after=2000-01-01", expCode: http.StatusOK,
+			expIDs: []int{1, 2, 3, 4, 5}, expTotal: 5},
+		{name: "CreatedAfterFuture", query: "?created_after=2999-01-01T00:00:00Z", expCode: http.StatusOK,
+			expIDs: []int{}, expTotal: 0},
+		{name: "Sort", query: "?sort=alpha&limit=1", expCode: http.StatusOK, expIDs: []int{3}, expTotal: 5,
+			expNext: "/todo?limit=1&offset=1&sort=alpha"},
+		{name: "InvalidLimit", query: "?limit=-1", expCode: http.StatusBadRequest},
+		{name: "InvalidDone", query: "?done=maybe", expCode: http.StatusBadRequest},
+		{name: "InvalidDate", query: "?created_after=yesterday", expCode: http.StatusBadRequest},
+		{name: "InvalidSort", query: "?sort=due", expCode: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.name,
+			func(t *testing.T) {
+				r, err := http.Get(url + "/todo" + tc.query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer r.Body.Close()
+
+				if r.StatusCode != tc.expCode {
+					t.Fatalf("Expected %q, got %q.",
+						http.StatusText(tc.expCode), http.StatusText(r.StatusCode))
+				}
+				if tc.expCode != http.StatusOK {
+					return
+				}
+
+				resp := struct {
+					Results      todo.List `json:"results"`
+					TotalResults int       `json:"total_results"`
+					Next         string    `json:"next"`
+					Prev         string    `json:"prev"`
+				}{}
+				if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+					t.Fatal(err)
+				}
+
+				ids := []int{}
+				for _, item := range resp.Results.Items {
+					ids = append(ids, item.ID)
+				}
+				if fmt.Sprint(ids) != fmt.Sprint(tc.expIDs) {
+					t.Errorf("Expected IDs %v, got %v.", tc.expIDs, ids)
+				}
+				if resp.TotalResults != tc.expTotal {
+					t.Errorf("Expected %d total results, got %d.", tc.expTotal, resp.TotalResults)
+				}
+				if resp.Next != tc.expNext || resp.Prev != tc.expPrev {
+					t.Errorf("Expected links %q and %q, got %q and %q.",
+						tc.expNext, tc.expPrev, resp.Next, resp.Prev)
+				}
+			})
+	}
+}
+
 func TestAdd(t *testing.T) {
 	url, cleanup := setupAPI(t)
 	defer cleanup()
@@ -208,8 +322,8 @@ func TestAdd(t *testing.T) {
 			}
 			r.Body.Close()
 
-			if resp.Results[0].Task != taskName {
-				t.Errorf("Expected %q, got %q.", taskName, resp.Results[0].Task)
+			if resp.Results.Items[0].Task != taskName {
+				t.Errorf("Expected %q, got %q.", taskName, resp.Results.Items[0].Task)
 			}
 		})
 }
@@ -259,13 +373,39 @@ func TestDelete(t *testing.T) {
 			}
 			r.Body.Close()
 
-			if len(resp.Results) != 1 {
-				t.Errorf("Expected 1 item, got %d.", len(resp.Results))
+			if len(resp.Results.Items) != 1 {
+				t.Errorf("Expected 1 item, got %d.", len(resp.Results.Items))
+			}
+
+			expTask := "Task number 2."
+			if resp.Results.Items[0].Task != expTask {
+				t.Errorf("Expected %q, got %q.", expTask, resp.Results.Items[0].Task)
+			}
+		})
+
+	t.Run(
+		"CheckIDAfterDelete",
+		func(t *testing.T) {
+			r, err := http.Get(url + "/todo/2")
+			if err != nil {
+				t.Error(err)
+			}
+
+			if r.StatusCode != http.StatusOK {
+				t.Fatalf("Expected %q, got %q.",
+					http.StatusText(http.StatusOK),
+					http.StatusText(r.StatusCode))
+			}
+
+			var resp todoResponse
+			if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
 			}
+			r.Body.Close()
 
 			expTask := "Task number 2."
-			if resp.Results[0].Task != expTask {
-				t.Errorf("Expected %q, got %q.", expTask, resp.Results[0].Task)
+			if resp.Results.Items[0].Task != expTask {
+				t.Errorf("Expected %q, got %q.", expTask, resp.Results.Items[0].Task)
 			}
 		})
 }
@@ -315,16 +455,1393 @@ func TestComplete(t *testing.T) {
 			}
 			r.Body.Close()
 
-			if len(resp.Results) != 2 {
-				t.Errorf("Expected 2 items, got %d.", len(resp.Results))
+			if len(resp.Results.Items) != 2 {
+				t.Errorf("Expected 2 items, got %d.", len(resp.Results.Items))
 			}
 
-			if !resp.Results[0].Done {
+			if !resp.Results.Items[0].Done {
 				t.Error("Expected Item 1 to be completed.")
 			}
 
-			if resp.Results[1].Done {
+			if resp.Results.Items[1].Done {
 				t.Error("Expected Item 2 not to be completed.")
 			}
 		})
 }
+
+func TestEditReopenMove(t *testing.T) {
+	url, cleanup := setupAPI(t)
+	defer cleanup()
+
+	send := func(t *testing.T, method, u string, body io.Reader, expStatus int) {
+		t.Helper()
+		req, err := http.NewRequest(method, u, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Body.Close()
+
+		if r.StatusCode != expStatus {
+			t.Fatalf("Expected %q, got %q.",
+				http.StatusText(expStatus),
+				http.StatusText(r.StatusCode))
+		}
+	}
+
+	t.Run(
+		"Edit",
+		func(t *testing.T) {
+			body := strings.NewReader(`{"task": "Task number 2, edited."}`)
+			send(t, http.MethodPut, url+"/todo/2", body, http.StatusNoContent)
+		})
+
+	t.Run(
+		"Reopen",
+		func(t *testing.T) {
+			send(t, http.MethodPatch, url+"/todo/1?complete", nil, http.StatusNoContent)
+			send(t, http.MethodPatch, url+"/todo/1?reopen", nil, http.StatusNoContent)
+		})
+
+	t.Run(
+		"Move",
+		func(t *testing.T) {
+			send(t, http.MethodPatch, url+"/todo/2?move=1", nil, http.StatusNoContent)
+			send(t, http.MethodPatch, url+"/todo/2?move=5", nil, http.StatusBadRequest)
+		})
+
+	t.Run(
+		"Check",
+		func(t *testing.T) {
+			r, err := http.Get(url + "/todo")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var resp todoResponse
+			if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+
+			expTask := "Task number 2, edited."
+			if resp.Results.Items[0].Task != expTask {
+				t.Errorf("Expected %q, got %q.", expTask, resp.Results.Items[0].Task)
+			}
+
+			if resp.Results.Items[1].Done {
+				t.Error("Expected Item 1 to be reopened.")
+			}
+		})
+}
+
+func TestSubtasks(t *testing.T) {
+	url, cleanup := setupAPI(t)
+	defer cleanup()
+
+	t.Run(
+		"AddSubtask",
+		func(t *testing.T) {
+			body := strings.NewReader(`{"task": "Subtask of 1.", "parent": 1, "blocked_by": [2]}`)
+			r, err := http.Post(url+"/todo", ContentApplicationJson, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.StatusCode != http.StatusCreated {
+				t.Fatalf("Expected %q, got %q.",
+					http.StatusText(http.StatusCreated),
+					http.StatusText(r.StatusCode))
+			}
+		})
+
+	t.Run(
+		"CheckHierarchy",
+		func(t *testing.T) {
+			r, err := http.Get(url + "/todo/3")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var resp todoResponse
+			if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+
+			item := resp.Results.Items[0]
+			if item.Parent != 1 || len(item.BlockedBy) != 1 || item.BlockedBy[0] != 2 {
+				t.Errorf("Expected parent 1 blocked by [2], got %d blocked by %v.",
+					item.Parent, item.BlockedBy)
+			}
+		})
+
+	t.Run(
+		"CompleteParent",
+		func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPatch, url+"/todo/1?complete", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+
+			if r.StatusCode != http.StatusConflict {
+				t.Fatalf("Expected %q, got %q.",
+					http.StatusText(http.StatusConflict),
+					http.StatusText(r.StatusCode))
+			}
+		})
+}
+
+func TestProjects(t *testing.T) {
+	url, cleanup := setupAPI(t)
+	defer cleanup()
+
+	t.Run(
+		"AddToProject",
+		func(t *testing.T) {
+			body := strings.NewReader(`{"task": "Work task."}`)
+			r, err := http.Post(url+"/projects/work/todo", ContentApplicationJson, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+
+			if r.StatusCode != http.StatusCreated {
+				t.Fatalf("Expected %q, got %q.",
+					http.StatusText(http.StatusCreated),
+					http.StatusText(r.StatusCode))
+			}
+		})
+
+	t.Run(
+		"GetProjectItem",
+		func(t *testing.T) {
+			r, err := http.Get(url + "/projects/work/todo/1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var resp todoResponse
+			if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+
+			if len(resp.Results.Items) != 1 || resp.Results.Items[0].Task != "Work task." {
+				t.Errorf("Expected %q, got %v.", "Work task.", resp.Results)
+			}
+		})
+
+	t.Run(
+		"DefaultProjectIsTodo",
+		func(t *testing.T) {
+			r, err := http.Get(url + "/projects/default/todo")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var resp todoResponse
+			if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+
+			if len(resp.Results.Items) != 2 {
+				t.Errorf("Expected 2 items, got %d.", len(resp.Results.Items))
+			}
+		})
+
+	t.Run(
+		"ListProjects",
+		func(t *testing.T) {
+			r, err := http.Get(url + "/projects")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var resp struct {
+				Results []projectSummary `json:"results"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+
+			expected := []projectSummary{
+				{Name: todo.DefaultProject, Open: 2, Total: 2},
+				{Name: "work", Open: 1, Total: 1},
+			}
+			if fmt.Sprint(resp.Results) != fmt.Sprint(expected) {
+				t.Errorf("Expected %v, got %v.", expected, resp.Results)
+			}
+		})
+
+	t.Run(
+		"UnknownPath",
+		func(t *testing.T) {
+			r, err := http.Get(url + "/projects/work/items")
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+
+			if r.StatusCode != http.StatusNotFound {
+				t.Errorf("Expected %q, got %q.",
+					http.StatusText(http.StatusNotFound),
+					http.StatusText(r.StatusCode))
+			}
+		})
+}
+
+func TestSync(t *testing.T) {
+	url, cleanup := setupAPI(t)
+	defer cleanup()
+
+	local := storage.NewInMemory()
+	remote := storage.NewRemote(url, todo.DefaultProject, nil)
+
+	sync := func(t *testing.T) todo.SyncResult {
+		t.Helper()
+		result, err := todo.Sync(local, remote, remote.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	send := func(t *testing.T, method, path, body string) {
+		t.Helper()
+		req, err := http.NewRequest(method, url+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Body.Close()
+		if r.StatusCode != http.StatusNoContent {
+			t.Fatalf("Expected %q, got %q.",
+				http.StatusText(http.StatusNoContent),
+				http.StatusText(r.StatusCode))
+		}
+	}
+
+	t.Run(
+		"PullAll",
+		func(t *testing.T) {
+			result := sync(t)
+			if result.Pulled != 2 || result.Pushed != 0 {
+				t.Errorf("Expected 2 pulled, got %+v.", result)
+			}
+
+			l := todo.List{}
+			local.Load(&l)
+			if len(l.Items) != 2 || l.Items[1].Task != "Task number 2." {
+				t.Errorf("Expected the remote items, got %v.", l)
+			}
+		})
+
+	t.Run(
+		"PushAndPull",
+		func(t *testing.T) {
+			if err := todo.Update(local, func(l *todo.List) error {
+				l.Add("Local task.")
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			send(t, http.MethodPatch, "/todo/1?complete", "")
+
+			result := sync(t)
+			if result.Pulled != 1 || result.Pushed != 1 || len(result.Conflicts) != 0 {
+				t.Errorf("Expected 1 pulled and 1 pushed, got %+v.", result)
+			}
+
+			l := todo.List{}
+			local.Load(&l)
+			if !l.Items[0].Done {
+				t.Errorf("Expected item 1 to be completed locally.")
+			}
+
+			rl := todo.List{}
+			if err := remote.Load(&rl); err != nil {
+				t.Fatal(err)
+			}
+			if len(rl.Items) != 3 || rl.Items[2].Task != "Local task." {
+				t.Errorf("Expected the local item on the server, got %v.", rl)
+			}
+		})
+
+	t.Run(
+		"Conflict",
+		func(t *testing.T) {
+			if err := todo.Update(local, func(l *todo.List) error {
+				return l.Edit(2, "Local edit.")
+			}); err != nil {
+				t.Fatal(err)
+			}
+			send(t, http.MethodPut, "/todo/2", `{"task": "Remote edit."}`)
+
+			result := sync(t)
+			expected := []todo.Conflict{{ID: 2, Task: "Local edit.", Reason: todo.ConflictChanged}}
+			if fmt.Sprint(result.Conflicts) != fmt.Sprint(expected) {
+				t.Errorf("Expected %v, got %v.", expected, result.Conflicts)
+			}
+
+			l, rl := todo.List{}, todo.List{}
+			local.Load(&l)
+			if err := remote.Load(&rl); err != nil {
+				t.Fatal(err)
+			}
+			if l.Items[1].Task != "Local edit." || rl.Items[1].Task != "Remote edit." {
+				t.Errorf("Expected both edits to be kept, got %q and %q.", l.Items[1].Task, rl.Items[1].Task)
+			}
+		})
+
+	t.Run(
+		"RemoteChanged",
+		func(t *testing.T) {
+			if err := todo.Update(local, func(l *todo.List) error {
+				l.Add("Racing task.")
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			// Another client reopens item 1 between the read and the write of the sync
+			changed := false
+			client := &http.Client{Transport: roundTripper(func(r *http.Request) (*http.Response, error) {
+				if r.Method == http.MethodPut && !changed {
+					changed = true
+					send(t, http.MethodPatch, "/todo/1?reopen", "")
+				}
+				return http.DefaultTransport.RoundTrip(r)
+			})}
+			racing := storage.NewRemote(url, todo.DefaultProject, client)
+
+			result, err := todo.Sync(local, racing, remote.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Pulled != 1 || result.Pushed != 1 {
+				t.Errorf("Expected 1 pulled and 1 pushed, got %+v.", result)
+			}
+
+			l, rl := todo.List{}, todo.List{}
+			local.Load(&l)
+			if err := remote.Load(&rl); err != nil {
+				t.Fatal(err)
+			}
+			if l.Items[0].Done || rl.Items[0].Done {
+				t.Errorf("Expected item 1 to be reopened on both sides.")
+			}
+			if len(rl.Items) != len(l.Items) || rl.Items[len(rl.Items)-1].Task != "Racing task." {
+				t.Errorf("Expected the local item on the server, got %v.", rl)
+			}
+		})
+
+	t.Run(
+		"DeletedHighestID",
+		func(t *testing.T) {
+			rl := todo.List{}
+			if err := remote.Load(&rl); err != nil {
+				t.Fatal(err)
+			}
+			deleted := rl.Items[len(rl.Items)-1].ID
+			req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/todo/%d", url, deleted), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+
+			// A new client syncs a list it did not change, so it sends nothing
+			puts := 0
+			client := &http.Client{Transport: roundTripper(func(r *http.Request) (*http.Response, error) {
+				if r.Method == http.MethodPut {
+					puts++
+				}
+				return http.DefaultTransport.RoundTrip(r)
+			})}
+			fresh := storage.NewInMemory()
+			counted := storage.NewRemote(url, todo.DefaultProject, client)
+			for range 2 {
+				if _, err := todo.Sync(fresh, counted, counted.String()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if puts != 0 {
+				t.Errorf("Expected no PUT for an unchanged list, got %d.", puts)
+			}
+
+			// Neither the client nor the server gives the deleted ID again
+			l := todo.List{}
+			if err := todo.Update(fresh, func(fl *todo.List) error {
+				fl.Add("Fresh task.")
+				l = *fl
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if id := l.Items[len(l.Items)-1].ID; id <= deleted {
+				t.Errorf("Expected a local ID above %d, got %d.", deleted, id)
+			}
+
+			// Even after a client replaced the list without the next ID
+			if err := remote.Load(&rl); err != nil {
+				t.Fatal(err)
+			}
+			rl.NextID = 0
+			if err := remote.Save(&rl); err != nil {
+				t.Fatal(err)
+			}
+			r, err = http.Post(url+"/todo", ContentApplicationJson, strings.NewReader(`{"task": "Server task."}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+			if err := remote.Load(&rl); err != nil {
+				t.Fatal(err)
+			}
+			if id := rl.Items[len(rl.Items)-1].ID; id <= deleted {
+				t.Errorf("Expected a server ID above %d, got %d.", deleted, id)
+			}
+		})
+
+	t.Run(
+		"ReplaceInvalid",
+		func(t *testing.T) {
+			body := strings.NewReader(`[{"ID": 1, "Task": "a"}, {"ID": 1, "Task": "b"}]`)
+			req, err := http.NewRequest(http.MethodPut, url+"/todo", body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+
+			if r.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected %q, got %q.",
+					http.StatusText(http.StatusBadRequest),
+					http.StatusText(r.StatusCode))
+			}
+		})
+}
+
+// Sends requests with the function, so tests can act before them
+type roundTripper func(r *http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestAttachments(t *testing.T) {
+	url, cleanup := setupAPI(t)
+	defer cleanup()
+
+	t.Run(
+		"Upload",
+		func(t *testing.T) {
+			body := strings.NewReader("hello\n")
+			r, err := http.Post(url+"/todo/1/attachments?name=notes/hello.txt", ContentTextPlain, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+
+			if r.StatusCode != http.StatusCreated {
+				t.Fatalf("Expected %q, got %q.",
+					http.StatusText(http.StatusCreated),
+					http.StatusText(r.StatusCode))
+			}
+		})
+
+	t.Run(
+		"Metadata",
+		func(t *testing.T) {
+			r, err := http.Get(url + "/todo/1/attachments")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var resp attachmentsResponse
+			if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+
+			expected := []todo.Attachment{{
+				Path:   "notes/hello.txt",
+				Size:   6,
+				SHA256: "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03",
+			}}
+			if fmt.Sprint(resp.Results) != fmt.Sprint(expected) {
+				t.Errorf("Expected %v, got %v.", expected, resp.Results)
+			}
+		})
+
+	t.Run(
+		"Content",
+		func(t *testing.T) {
+			r, err := http.Get(url + "/todo/1/attachments/1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Body.Close()
+
+			content, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if r.StatusCode != http.StatusOK {
+				t.Fatalf("Expected %q, got %q.",
+					http.StatusText(http.StatusOK),
+					http.StatusText(r.StatusCode))
+			}
+			if string(content) != "hello\n" {
+				t.Errorf("Expected %q, got %q.", "hello\n", content)
+			}
+			if ct := r.Header.Get(ContentType); !strings.HasPrefix(ct, ContentTextPlain) {
+				t.Errorf("Expected content type %q, got %q.", ContentTextPlain, ct)
+			}
+		})
+
+	t.Run(
+		"TooLarge",
+		func(t *testing.T) {
+			defer func(size int64) { maxUploadSize = size }(maxUploadSize)
+			maxUploadSize = 4
+
+			body := strings.NewReader("hello\n")
+			r, err := http.Post(url+"/todo/1/attachments?name=large.txt", ContentTextPlain, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+
+			if r.StatusCode != http.StatusRequestEntityTooLarge {
+				t.Errorf("Expected %q, got %q.",
+					http.StatusText(http.StatusRequestEntityTooLarge),
+					http.StatusText(r.StatusCode))
+			}
+		})
+
+	t.Run(
+		"Truncated",
+		func(t *testing.T) {
+			conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			// The body ends before its length
+			fmt.Fprintf(conn, "POST /todo/1/attachments?name=short.txt HTTP/1.1\r\n"+
+				"Host: localhost\r\nContent-Length: 100\r\n\r\nhello\n")
+			conn.(*net.TCPConn).CloseWrite()
+
+			r, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+
+			if r.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected %q, got %q.",
+					http.StatusText(http.StatusBadRequest),
+					http.StatusText(r.StatusCode))
+			}
+		})
+
+	t.Run(
+		"ContentNotFound",
+		func(t *testing.T) {
+			r, err := http.Get(url + "/todo/1/attachments/2")
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+
+			if r.StatusCode != http.StatusNotFound {
+				t.Errorf("Expected %q, got %q.",
+					http.StatusText(http.StatusNotFound),
+					http.StatusText(r.StatusCode))
+			}
+		})
+}
+
+func TestV2(t *testing.T) {
+	url, cleanup := setupAPI(t)
+	defer cleanup()
+
+	send := func(t *testing.T, method, path, body string, expCode int) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, url+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.StatusCode != expCode {
+			t.Fatalf("Expected %q, got %q.", http.StatusText(expCode), http.StatusText(r.StatusCode))
+		}
+		return r
+	}
+
+	decode := func(t *testing.T, r *http.Response, v any) {
+		t.Helper()
+		defer r.Body.Close()
+		if ct := r.Header.Get(ContentType); ct != ContentApplicationJson {
+			t.Fatalf("Expected %q, got %q.", ContentApplicationJson, ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run(
+		"Create",
+		func(t *testing.T) {
+			body := `{"task": "Task number 3.", "priority": "high", "due": "2026-11-01", "tags": ["ops"]}`
+			r := send(t, http.MethodPost, "/v2/todos", body, http.StatusCreated)
+
+			if loc := r.Header.Get("Location"); loc != "/v2/todos/3" {
+				t.Errorf("Expected location %q, got %q.", "/v2/todos/3", loc)
+			}
+
+			item := todo.ItemView{}
+			decode(t, r, &item)
+			if item.ID != 3 || item.Task != "Task number 3." || item.Priority != "high" {
+				t.Errorf("Expected the created item, got %+v.", item)
+			}
+			if item.Due == nil || item.Due.Format(todo.DateFormat) != "2026-11-01" {
+				t.Errorf("Expected due date 2026-11-01, got %v.", item.Due)
+			}
+		})
+
+	t.Run(
+		"Get",
+		func(t *testing.T) {
+			resp := struct {
+				Results      []todo.ItemView `json:"results"`
+				TotalResults int             `json:"total_results"`
+			}{}
+			decode(t, send(t, http.MethodGet, "/v2/todos", "", http.StatusOK), &resp)
+			if resp.TotalResults != 3 || resp.Results[0].Task != "Task number 1." {
+				t.Errorf("Expected 3 items, got %+v.", resp)
+			}
+
+			page := struct {
+				Results      []todo.ItemView `json:"results"`
+				TotalResults int             `json:"total_results"`
+				Next         string          `json:"next"`
+			}{}
+			decode(t, send(t, http.MethodGet, "/v2/todos?limit=1&done=false", "", http.StatusOK), &page)
+			if len(page.Results) != 1 || page.TotalResults != 3 || page.Next != "/v2/todos?done=false&limit=1&offset=1" {
+				t.Errorf("Expected the first of 3 open items, got %+v.", page)
+			}
+
+			item := todo.ItemView{}
+			decode(t, send(t, http.MethodGet, "/v2/todos/2", "", http.StatusOK), &item)
+			if item.Task != "Task number 2." {
+				t.Errorf("Expected %q, got %q.", "Task number 2.", item.Task)
+			}
+		})
+
+	t.Run(
+		"Update",
+		func(t *testing.T) {
+			body := `{"task": "Task three.", "done": true, "notes": "Done early"}`
+			item := todo.ItemView{}
+			decode(t, send(t, http.MethodPut, "/v2/todos/3", body, http.StatusOK), &item)
+
+			// Fields missing in a full update are cleared
+			if item.Task != "Task three." || !item.Done || item.Priority != "" || len(item.Tags) != 0 {
+				t.Errorf("Expected the item to be replaced, got %+v.", item)
+			}
+		})
+
+	t.Run(
+		"PartialUpdate",
+		func(t *testing.T) {
+			item := todo.ItemView{}
+			r := send(t, http.MethodPatch, "/v2/todos/3", `{"done": false}`, http.StatusOK)
+			decode(t, r, &item)
+			if item.Task != "Task three." || item.Done || item.Notes != "Done early" {
+				t.Errorf("Expected only done to change, got %+v.", item)
+			}
+		})
+
+	t.Run(
+		"Project",
+		func(t *testing.T) {
+			r := send(t, http.MethodPost, "/v2/projects/work/todos", `{"task": "Work task."}`, http.StatusCreated)
+			r.Body.Close()
+			if loc := r.Header.Get("Location"); loc != "/v2/projects/work/todos/1" {
+				t.Errorf("Expected location %q, got %q.", "/v2/projects/work/todos/1", loc)
+			}
+
+			// The v1 API serves the same lists
+			resp := struct {
+				Results todo.List `json:"results"`
+			}{}
+			decode(t, send(t, http.MethodGet, "/projects/work/todo", "", http.StatusOK), &resp)
+			if len(resp.Results.Items) != 1 || resp.Results.Items[0].Task != "Work task." {
+				t.Errorf("Expected the work task, got %v.", resp.Results)
+			}
+		})
+
+	t.Run(
+		"Delete",
+		func(t *testing.T) {
+			send(t, http.MethodDelete, "/v2/todos/3", "", http.StatusNoContent).Body.Close()
+			send(t, http.MethodGet, "/v2/todos/3", "", http.StatusNotFound).Body.Close()
+		})
+
+	errorCases := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		expCode int
+	}{
+		{name: "NotFound", method: http.MethodGet, path: "/v2/todos/500", expCode: http.StatusNotFound},
+		{name: "InvalidID", method: http.MethodGet, path: "/v2/todos/abc", expCode: http.StatusBadRequest},
+		{name: "MissingTask", method: http.MethodPost, path: "/v2/todos", body: `{}`, expCode: http.StatusBadRequest},
+		{name: "UnknownField", method: http.MethodPatch, path: "/v2/todos/1", body: `{"taks": "x"}`, expCode: http.StatusBadRequest},
+		{name: "InvalidPriority", method: http.MethodPatch, path: "/v2/todos/1", body: `{"priority": "urgent"}`, expCode: http.StatusBadRequest},
+		{name: "MethodNotAllowed", method: http.MethodDelete, path: "/v2/todos", expCode: http.StatusMethodNotAllowed},
+		{name: "UnknownPath", method: http.MethodGet, path: "/v2/items", expCode: http.StatusNotFound},
+	}
+
+	for _, tc := range errorCases {
+		t.Run(
+			tc.name,
+			func(t *testing.T) {
+				resp := struct {
+					Error struct {
+						Status  int    `json:"status"`
+						Message string `json:"message"`
+					} `json:"error"`
+				}{}
+				decode(t, send(t, tc.method, tc.path, tc.body, tc.expCode), &resp)
+
+				if resp.Error.Status != tc.expCode || resp.Error.Message == "" {
+					t.Errorf("Expected error envelope with status %d, got %+v.", tc.expCode, resp)
+				}
+			})
+	}
+}
+
+func TestAuth(t *testing.T) {
+	auth := &authConfig{Secret: "s3cret", Keys: map[string]string{"alice-key": "alice"}}
+	dir := t.TempDir()
+	stores := &cachedStorages{}
+	ts := httptest.NewServer(newAuthHandler(auth, userHandlers(dir+"/todo.json", dir, stores.open)))
+	defer ts.Close()
+
+	bobToken, err := auth.issueToken("bob", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := auth.issueToken("bob", time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(t *testing.T, method, path, token, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	testCases := []struct {
+		name    string
+		token   string
+		expCode int
+	}{
+		{name: "MissingToken", token: "", expCode: http.StatusUnauthorized},
+		{name: "UnknownKey", token: "mallory-key", expCode: http.StatusUnauthorized},
+		{name: "ExpiredToken", token: expired, expCode: http.StatusUnauthorized},
+		{name: "ForgedToken", token: strings.Replace(bobToken, "bob", "alice", 1), expCode: http.StatusUnauthorized},
+		{name: "APIKey", token: "alice-key", expCode: http.StatusOK},
+		{name: "SignedToken", token: bobToken, expCode: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.name,
+			func(t *testing.T) {
+				r := send(t, http.MethodGet, "/todo", tc.token, "")
+				r.Body.Close()
+				if r.StatusCode != tc.expCode {
+					t.Errorf("Expected %q, got %q.",
+						http.StatusText(tc.expCode), http.StatusText(r.StatusCode))
+				}
+				if tc.expCode == http.StatusUnauthorized && r.Header.Get("WWW-Authenticate") == "" {
+					t.Errorf("Expected a WWW-Authenticate header.")
+				}
+			})
+	}
+
+	t.Run(
+		"JSONError",
+		func(t *testing.T) {
+			r := send(t, http.MethodGet, "/v2/todos", "", "")
+			defer r.Body.Close()
+			if ct := r.Header.Get(ContentType); r.StatusCode != http.StatusUnauthorized || ct != ContentApplicationJson {
+				t.Errorf("Expected a JSON 401 error, got %d %q.", r.StatusCode, ct)
+			}
+		})
+
+	t.Run(
+		"IsolatedLists",
+		func(t *testing.T) {
+			r := send(t, http.MethodPost, "/todo", "alice-key", `{"task": "Alice task."}`)
+			r.Body.Close()
+			if r.StatusCode != http.StatusCreated {
+				t.Fatalf("Expected %q, got %q.",
+					http.StatusText(http.StatusCreated), http.StatusText(r.StatusCode))
+			}
+
+			count := func(token string) int {
+				r := send(t, http.MethodGet, "/todo", token, "")
+				defer r.Body.Close()
+				resp := struct {
+					TotalResults int `json:"total_results"`
+				}{}
+				if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+					t.Fatal(err)
+				}
+				return resp.TotalResults
+			}
+
+			if n := count("alice-key"); n != 1 {
+				t.Errorf("Expected 1 item for alice, got %d.", n)
+			}
+			if n := count(bobToken); n != 0 {
+				t.Errorf("Expected no items for bob, got %d.", n)
+			}
+			if _, err := os.Stat(dir + "/todo.alice.json"); err != nil {
+				t.Errorf("Expected the list of alice in its own file: %s", err)
+			}
+		})
+}
+
+func TestETag(t *testing.T) {
+	url, cleanup := setupAPI(t)
+	defer cleanup()
+
+	send := func(t *testing.T, method, path string, header map[string]string, expCode int) string {
+		t.Helper()
+		req, err := http.NewRequest(method, url+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Body.Close()
+		if r.StatusCode != expCode {
+			t.Fatalf("Expected %q, got %q.", http.StatusText(expCode), http.StatusText(r.StatusCode))
+		}
+		return r.Header.Get("ETag")
+	}
+
+	listTag := send(t, http.MethodGet, "/todo", nil, http.StatusOK)
+	itemTag := send(t, http.MethodGet, "/todo/1", nil, http.StatusOK)
+	if listTag == "" || itemTag == "" || listTag == itemTag {
+		t.Fatalf("Expected different list and item ETags, got %q and %q.", listTag, itemTag)
+	}
+
+	t.Run(
+		"NotModified",
+		func(t *testing.T) {
+			send(t, http.MethodGet, "/todo", map[string]string{"If-None-Match": listTag}, http.StatusNotModified)
+			send(t, http.MethodGet, "/todo/1", map[string]string{"If-None-Match": "W/" + itemTag}, http.StatusNotModified)
+			send(t, http.MethodGet, "/v2/todos/1", map[string]string{"If-None-Match": itemTag}, http.StatusNotModified)
+			send(t, http.MethodGet, "/todo/2", map[string]string{"If-None-Match": itemTag}, http.StatusOK)
+		})
+
+	t.Run(
+		"CompleteIfMatch",
+		func(t *testing.T) {
+			send(t, http.MethodPatch, "/todo/1?complete", map[string]string{"If-Match": `"stale"`},
+				http.StatusPreconditionFailed)
+
+			newTag := send(t, http.MethodPatch, "/todo/1?complete", map[string]string{"If-Match": itemTag},
+				http.StatusNoContent)
+			if newTag == "" || newTag == itemTag {
+				t.Errorf("Expected a new ETag, got %q.", newTag)
+			}
+
+			// The new version is the one served afterwards
+			if got := send(t, http.MethodGet, "/todo/1", nil, http.StatusOK); got != newTag {
+				t.Errorf("Expected ETag %q, got %q.", newTag, got)
+			}
+			send(t, http.MethodGet, "/todo", map[string]string{"If-None-Match": listTag}, http.StatusOK)
+		})
+
+	t.Run(
+		"DeleteIfMatch",
+		func(t *testing.T) {
+			// The item changed since itemTag was read
+			send(t, http.MethodDelete, "/todo/1", map[string]string{"If-Match": itemTag},
+				http.StatusPreconditionFailed)
+			send(t, http.MethodDelete, "/v2/todos/1", map[string]string{"If-Match": itemTag},
+				http.StatusPreconditionFailed)
+
+			tag := send(t, http.MethodGet, "/todo/1", nil, http.StatusOK)
+			send(t, http.MethodDelete, "/todo/1", map[string]string{"If-Match": tag}, http.StatusNoContent)
+			send(t, http.MethodDelete, "/todo/2", map[string]string{"If-Match": "*"}, http.StatusNoContent)
+		})
+}
+
+func TestCachedStorage(t *testing.T) {
+	filename := t.TempDir() + "/todo.json"
+	backend := storage.NewJSONFile(filename)
+	c := newCachedStorage(backend, time.Hour)
+
+	ts := httptest.NewServer(newMux(c, ""))
+	defer ts.Close()
+
+	for _, path := range []string{"/todo", "/projects/work/todo"} {
+		r, err := http.Post(ts.URL+path, ContentApplicationJson, strings.NewReader(`{"task": "Task."}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Body.Close()
+	}
+
+	stored := func() int {
+		t.Helper()
+		l := todo.List{}
+		if err := backend.Load(&l); err != nil {
+			t.Fatal(err)
+		}
+		return len(l.Items)
+	}
+
+	// Writes wait for the delay
+	if n := stored(); n != 0 {
+		t.Errorf("Expected no stored items before the flush, got %d.", n)
+	}
+
+	r, err := http.Get(ts.URL + "/todo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := struct {
+		TotalResults int `json:"total_results"`
+	}{}
+	json.NewDecoder(r.Body).Decode(&resp)
+	r.Body.Close()
+	if resp.TotalResults != 1 {
+		t.Errorf("Expected the cached item, got %d items.", resp.TotalResults)
+	}
+
+	names, err := c.Projects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(names) != "[default work]" {
+		t.Errorf("Expected the cached projects, got %v.", names)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := stored(); n != 1 {
+		t.Errorf("Expected 1 stored item after the flush, got %d.", n)
+	}
+	work, _ := backend.Project("work")
+	l := todo.List{}
+	if err := work.Load(&l); err != nil || len(l.Items) != 1 {
+		t.Errorf("Expected the work item to be stored, got %v, %v.", l, err)
+	}
+
+	// Without a delay every change is written at once
+	now := newCachedStorage(backend, 0)
+	if err := todo.Update(now, func(l *todo.List) error { l.Add("Task 2."); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if n := stored(); n != 2 {
+		t.Errorf("Expected 2 stored items, got %d.", n)
+	}
+}
+
+// Storage whose saves fail a number of times before they succeed
+type flakyStorage struct {
+	todo.ProjectStorage
+
+	mu    sync.Mutex
+	fails int
+}
+
+type flakyProject struct {
+	todo.Storage
+	s *flakyStorage
+}
+
+func (s *flakyStorage) Project(name string) (todo.Storage, error) {
+	p, err := s.ProjectStorage.Project(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &flakyProject{Storage: p, s: s}, nil
+}
+
+func (p *flakyProject) Save(l *todo.List) error {
+	p.s.mu.Lock()
+	defer p.s.mu.Unlock()
+
+	if p.s.fails > 0 {
+		p.s.fails--
+		return errors.New("disk full")
+	}
+
+	return p.Storage.Save(l)
+}
+
+// Tests that failed writes of the cache are retried without other changes
+func TestCachedStorageRetry(t *testing.T) {
+	defer func(d time.Duration) { minRetryDelay = d }(minRetryDelay)
+	minRetryDelay = 10 * time.Millisecond
+
+	backend := storage.NewJSONFile(t.TempDir() + "/todo.json")
+	c := newCachedStorage(&flakyStorage{ProjectStorage: backend, fails: 2}, time.Hour)
+	defer c.Close()
+
+	if err := todo.Update(c, func(l *todo.List) error { l.Add("Task."); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Flush(); err == nil {
+		t.Fatal("Expected the first write to fail.")
+	}
+
+	l := todo.List{}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		if err := backend.Load(&l); err != nil {
+			t.Fatal(err)
+		}
+		if len(l.Items) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(l.Items) != 1 {
+		t.Errorf("Expected the retries to write the item, got %v.", l.Items)
+	}
+}
+
+// Tests that changes made by other processes between a read and a write of the cache are kept
+func TestCachedStorageOutsideChange(t *testing.T) {
+	filename := t.TempDir() + "/todo.json"
+	backend := storage.NewJSONFile(filename)
+	if err := todo.Update(backend, func(l *todo.List) error { l.Add("Old task."); return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newCachedStorage(backend, time.Hour)
+	if err := todo.Update(c, func(l *todo.List) error { l.Add("Server task."); return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	// The CLI changes the file before the server writes it
+	err := todo.Update(backend, func(l *todo.List) error {
+		l.Add("CLI task.")
+		return l.Edit(1, "Edited task.")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks := func(s todo.Storage) string {
+		t.Helper()
+		l := todo.List{}
+		if err := s.Load(&l); err != nil {
+			t.Fatal(err)
+		}
+		ts := []string{}
+		for _, v := range l.Views() {
+			ts = append(ts, fmt.Sprintf("%d:%s", v.ID, v.Task))
+		}
+		sort.Strings(ts)
+		return strings.Join(ts, " ")
+	}
+
+	// The new item of the server gets another ID than the one of the CLI
+	expected := "1:Edited task. 2:CLI task. 3:Server task."
+	if got := tasks(backend); got != expected {
+		t.Errorf("Expected %q, got %q.", expected, got)
+	}
+	if got := tasks(c); got != expected {
+		t.Errorf("Expected the cache to have the changes %q, got %q.", expected, got)
+	}
+
+	// The next write starts from the merged list
+	if err := todo.Update(c, func(l *todo.List) error { return l.Delete(2) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected = "1:Edited task. 3:Server task."
+	if got := tasks(backend); got != expected {
+		t.Errorf("Expected %q, got %q.", expected, got)
+	}
+}
+
+// Tests that the changes made through the API can be undone with the history of the storage
+func TestHistory(t *testing.T) {
+	testCases := []struct {
+		name  string
+		cache bool
+	}{
+		{name: "Storage"},
+		{name: "Cached", cache: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			backend := storage.NewJSONFile(t.TempDir() + "/todo.json")
+			var store todo.ProjectStorage = backend
+			c := newCachedStorage(backend, time.Hour)
+			if tc.cache {
+				store = c
+			}
+
+			ts := httptest.NewServer(newMux(store, ""))
+			defer ts.Close()
+
+			send := func(method, path, body string) {
+				t.Helper()
+				req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				r, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r.Body.Close()
+				if r.StatusCode >= 300 {
+					t.Fatalf("Expected %s %s to succeed, got status %d.", method, path, r.StatusCode)
+				}
+			}
+
+			send(http.MethodPost, "/todo", `{"task": "Task 1."}`)
+			send(http.MethodPost, "/v2/todos", `{"task": "Task 2."}`)
+			send(http.MethodPatch, "/todo/1?complete", "")
+			send(http.MethodDelete, "/v2/todos/2", "")
+			if err := c.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			h := todo.History{}
+			if err := backend.LoadHistory(&h); err != nil {
+				t.Fatal(err)
+			}
+			if len(h.Entries) != 4 {
+				t.Fatalf("Expected 4 recorded operations, got %d.", len(h.Entries))
+			}
+
+			if err := todo.Undo(backend); err != nil {
+				t.Fatal(err)
+			}
+			l := todo.List{}
+			if err := backend.Load(&l); err != nil {
+				t.Fatal(err)
+			}
+			if len(l.Items) != 2 || l.Items[1].Task != "Task 2." {
+				t.Errorf("Expected the deleted item to be restored, got %v.", l.Items)
+			}
+		})
+	}
+}
+
+// Benchmarks reading and changing a large list with the storage read
+// and written by every request, and with the cached storage
+func BenchmarkServer(b *testing.B) {
+	log.SetOutput(io.Discard)
+
+	l := todo.List{}
+	for i := 0; i < 1000; i++ {
+		l.Add(fmt.Sprintf("Task number %d.", i), todo.WithTags("bench"))
+	}
+
+	stores := map[string]func(filename string) todo.ProjectStorage{
+		"File": func(filename string) todo.ProjectStorage {
+			return storage.NewJSONFile(filename)
+		},
+		"Cached": func(filename string) todo.ProjectStorage {
+			c := newCachedStorage(storage.NewJSONFile(filename), time.Second)
+			b.Cleanup(func() {
+				if err := c.Close(); err != nil {
+					b.Error(err)
+				}
+			})
+			return c
+		},
+	}
+
+	for _, name := range []string{"File", "Cached"} {
+		filename := b.TempDir() + "/todo.json"
+		if err := l.Save(filename); err != nil {
+			b.Fatal(err)
+		}
+		h := newMux(stores[name](filename), "")
+
+		b.Run("GetOne/"+name, func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					w := httptest.NewRecorder()
+					h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/todo/500", nil))
+					if w.Code != http.StatusOK {
+						b.Fatalf("Expected %q, got %q.", http.StatusText(http.StatusOK), http.StatusText(w.Code))
+					}
+				}
+			})
+		})
+
+		b.Run("Complete/"+name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				path := fmt.Sprintf("/todo/%d?complete", i%1000+1)
+				if i%2000 >= 1000 {
+					path = fmt.Sprintf("/todo/%d?reopen", i%1000+1)
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, path, nil))
+				if w.Code != http.StatusNoContent {
+					b.Fatalf("Expected %q, got %q.", http.StatusText(http.StatusNoContent), http.StatusText(w.Code))
+				}
+			}
+		})
+	}
+}
+
+func TestHealth(t *testing.T) {
+	h := &health{}
+	a := &authConfig{Secret: "secret"}
+	handler := h.handler(newAuthHandler(a, func(user string) (http.Handler, error) {
+		return http.NotFoundHandler(), nil
+	}))
+
+	testCases := []struct {
+		name    string
+		path    string
+		ready   bool
+		expCode int
+	}{
+		{name: "Live", path: "/healthz", expCode: http.StatusOK},
+		{name: "NotReady", path: "/readyz", expCode: http.StatusServiceUnavailable},
+		{name: "Ready", path: "/readyz", ready: true, expCode: http.StatusOK},
+		{name: "APIStillAuthenticated", path: "/todo", ready: true, expCode: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h.setReady(tc.ready)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			if w.Code != tc.expCode {
+				t.Errorf("Expected %q, got %q.", http.StatusText(tc.expCode), http.StatusText(w.Code))
+			}
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&out, nil))
+	h := accessLog(logger, newMux(storage.NewJSONFile(t.TempDir()+"/todo.json"), ""))
+
+	testCases := []struct {
+		name      string
+		path      string
+		requestID string
+		expCode   int
+	}{
+		{name: "NewID", path: "/todo", expCode: http.StatusOK},
+		{name: "KeepID", path: "/todo/1", requestID: "abc123", expCode: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out.Reset()
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.requestID != "" {
+				req.Header.Set(RequestIDHeader, tc.requestID)
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			id := w.Header().Get(RequestIDHeader)
+			if id == "" || (tc.requestID != "" && id != tc.requestID) {
+				t.Fatalf("Expected request ID %q, got %q.", tc.requestID, id)
+			}
+
+			var entry struct {
+				Msg       string  `json:"msg"`
+				Method    string  `json:"method"`
+				Path      string  `json:"path"`
+				Status    int     `json:"status"`
+				Latency   float64 `json:"latency_ms"`
+				RequestID string  `json:"request_id"`
+			}
+			if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+				t.Fatalf("Expected one JSON line, got %q: %s", out.String(), err)
+			}
+
+			if entry.Msg != "request" || entry.Method != http.MethodGet || entry.Path != tc.path {
+				t.Errorf("Expected the request %s %s, got %+v.", http.MethodGet, tc.path, entry)
+			}
+			if entry.Status != tc.expCode {
+				t.Errorf("Expected status %d, got %d.", tc.expCode, entry.Status)
+			}
+			if entry.RequestID != id {
+				t.Errorf("Expected request ID %q, got %q.", id, entry.RequestID)
+			}
+		})
+	}
+}
@@ -6,8 +6,16 @@ import (
 	"time"                  // To worj with time functions (get current time)
 )
 
+// Page of a list. TotalResults counts the items of all pages,
+// and Next and Prev link to the other pages if there are any.
+// NextID is the NextID of the whole list, so syncing clients
+// do not give the IDs of items deleted on the server again.
 type todoResponse struct {
-	Results todo.List `json:"results"`
+	Results      todo.List
+	TotalResults int
+	Next         string
+	Prev         string
+	NextID       int
 }
 
 func (r *todoResponse) MarshalJSON() ([]byte, error) {
@@ -15,6 +23,53 @@ func (r *todoResponse) MarshalJSON() ([]byte, error) {
 		Results      todo.List `json:"results"`
 		Date         int64     `json:"date"`
 		TotalResults int       `json:"total_results"`
+		Next         string    `json:"next,omitempty"`
+		Prev         string    `json:"prev,omitempty"`
+		NextID       int       `json:"next_id,omitempty"`
+	}{
+		Results:      r.Results,
+		Date:         time.Now().Unix(),
+		TotalResults: r.TotalResults,
+		Next:         r.Next,
+		Prev:         r.Prev,
+		NextID:       r.NextID,
+	}
+	return json.Marshal(resp)
+}
+
+// Name of a project with the number of its items
+type projectSummary struct {
+	Name  string `json:"name"`
+	Open  int    `json:"open"`
+	Total int    `json:"total"`
+}
+
+type projectsResponse struct {
+	Results []projectSummary `json:"results"`
+}
+
+func (r *projectsResponse) MarshalJSON() ([]byte, error) {
+	resp := struct {
+		Results      []projectSummary `json:"results"`
+		Date         int64            `json:"date"`
+		TotalResults int              `json:"total_results"`
+	}{
+		Results:      r.Results,
+		Date:         time.Now().Unix(),
+		TotalResults: len(r.Results),
+	}
+	return json.Marshal(resp)
+}
+
+type attachmentsResponse struct {
+	Results []todo.Attachment `json:"results"`
+}
+
+func (r *attachmentsResponse) MarshalJSON() ([]byte, error) {
+	resp := struct {
+		Results      []todo.Attachment `json:"results"`
+		Date         int64             `json:"date"`
+		TotalResults int               `json:"total_results"`
 	}{
 		Results:      r.Results,
 		Date:         time.Now().Unix(),
@@ -22,3 +77,56 @@ func (r *todoResponse) MarshalJSON() ([]byte, error) {
 	}
 	return json.Marshal(resp)
 }
+
+// Item of the v2 API, sent as it is
+type itemResponse struct {
+	Item todo.ItemView
+}
+
+func (r *itemResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Item)
+}
+
+// Page of items of the v2 API, like todoResponse
+type itemsResponse struct {
+	Results      []todo.ItemView
+	TotalResults int
+	Next         string
+	Prev         string
+}
+
+func (r *itemsResponse) MarshalJSON() ([]byte, error) {
+	resp := struct {
+		Results      []todo.ItemView `json:"results"`
+		Date         int64           `json:"date"`
+		TotalResults int             `json:"total_results"`
+		Next         string          `json:"next,omitempty"`
+		Prev         string          `json:"prev,omitempty"`
+	}{
+		Results:      r.Results,
+		Date:         time.Now().Unix(),
+		TotalResults: r.TotalResults,
+		Next:         r.Next,
+		Prev:         r.Prev,
+	}
+	return json.Marshal(resp)
+}
+
+// Error envelope of the v2 API
+type errorResponse struct {
+	Status  int
+	Message string
+}
+
+func (r *errorResponse) MarshalJSON() ([]byte, error) {
+	type body struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	}
+	resp := struct {
+		Error body `json:"error"`
+	}{
+		Error: body{Status: r.Status, Message: r.Message},
+	}
+	return json.Marshal(resp)
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"encoding/json"         // To decode the items sent by clients
+	"errors"                // To detect open subtasks
+	"fmt"                   // To format error messages
+	"net/http"              // To deal with HTTP requests and responses
+	"rggo/interacting/todo" // todo application
+	"strconv"               // To compose the location of new items
+	"sync"                  // To use the type sync.RWMutex to prevent racing conditions when accessing to-do storage
+	"time"                  // To parse due dates
+)
+
+// Item sent to create or replace an item of the v2 API.
+// The due date is YYYY-MM-DD or RFC 3339; empty removes it.
+type itemRequest struct {
+	Task      string   `json:"task"`
+	Done      bool     `json:"done"`
+	Priority  string   `json:"priority"`
+	Due       string   `json:"due"`
+	Tags      []string `json:"tags"`
+	Notes     string   `json:"notes"`
+	Parent    int      `json:"parent"`
+	BlockedBy []int    `json:"blocked_by"`
+}
+
+// Fields sent to partially update an item of the v2 API. Missing fields are kept.
+type itemPatch struct {
+	Task     *string   `json:"task"`
+	Done     *bool     `json:"done"`
+	Priority *string   `json:"priority"`
+	Due      *string   `json:"due"`
+	Tags     *[]string `json:"tags"`
+	Notes    *string   `json:"notes"`
+}
+
+// Serves the v2 API. Items are JSON objects under /v2/todos and
+// /v2/projects/{project}/todos, and errors are JSON envelopes.
+func registerV2(m *http.ServeMux, store todo.ProjectStorage, l *sync.RWMutex) {
+	v2 := v2Router(store, l)
+	m.HandleFunc("/v2/todos", v2)
+	m.HandleFunc("/v2/todos/{id}", v2)
+	m.HandleFunc("/v2/projects/{project}/todos", v2)
+	m.HandleFunc("/v2/projects/{project}/todos/{id}", v2)
+
+	m.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		replyJSONError(w, r, http.StatusNotFound, "Not found")
+	})
+}
+
+func v2Router(store todo.ProjectStorage, l *sync.RWMutex) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		project := r.PathValue("project")
+		if project == "" {
+			project = todo.DefaultProject
+		}
+
+		ps, err := store.Project(project)
+		if err != nil {
+			replyJSONError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		defer lockFor(r, l)()
+
+		// Other processes may share the storage
+		if lk, ok := ps.(todo.Locker); ok {
+			if err := lk.Lock(); err != nil {
+				replyJSONError(w, r, http.StatusServiceUnavailable, err.Error())
+				return
+			}
+			defer lk.Unlock()
+		}
+
+		list := &todo.List{}
+		if err := ps.Load(list); err != nil {
+			replyJSONError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if r.PathValue("id") == "" {
+			if !checkPreconditions(w, r, etagOf(*list), replyJSONError) {
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+				getAllV2Handler(w, r, list)
+			case http.MethodPost:
+				createV2Handler(w, r, list, heldList{ps, list})
+			default:
+				replyMethodNotAllowed(w, r, http.MethodGet, http.MethodPost)
+			}
+			return
+		}
+
+		id, err := validateID(r.PathValue("id"), list)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				replyJSONError(w, r, http.StatusNotFound, err.Error())
+				return
+			}
+			replyJSONError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if !checkPreconditions(w, r, itemETag(list, id), replyJSONError) {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			replyJSONContent(w, r, http.StatusOK, &itemResponse{Item: viewOf(list, id)})
+		case http.MethodPut:
+			req := itemRequest{}
+			if !decodeV2(w, r, &req) {
+				return
+			}
+			if req.Task == "" {
+				replyJSONError(w, r, http.StatusBadRequest, "Missing task name")
+				return
+			}
+			updateV2Handler(w, r, list, id, req.patch(), heldList{ps, list})
+		case http.MethodPatch:
+			p := itemPatch{}
+			if !decodeV2(w, r, &p) {
+				return
+			}
+			if p.Task != nil && *p.Task == "" {
+				replyJSONError(w, r, http.StatusBadRequest, "Missing task name")
+				return
+			}
+			updateV2Handler(w, r, list, id, p, heldList{ps, list})
+		case http.MethodDelete:
+			err := todo.Update(heldList{ps, list}, func(l *todo.List) error {
+				return l.Delete(id)
+			})
+			if err != nil {
+				replyJSONError(w, r, http.StatusInternalServerError, err.Error())
+				return
+			}
+			w.Header().Del("ETag")
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			replyMethodNotAllowed(w, r,
+				http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete)
+		}
+	}
+}
+
+// Sends the page of the items selected by the query params, like getAllHandler
+func getAllV2Handler(w http.ResponseWriter, r *http.Request, list *todo.List) {
+	lq, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		replyJSONError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, total := lq.page(list)
+	resp := &itemsResponse{
+		Results:      page.Views(),
+		TotalResults: total,
+	}
+	resp.Next, resp.Prev = lq.links(r.RequestURI, total)
+
+	replyJSONContent(w, r, http.StatusOK, resp)
+}
+
+// Adds the item and replies with it and its location
+func createV2Handler(w http.ResponseWriter, r *http.Request, list *todo.List, store todo.Storage) {
+	req := itemRequest{}
+	if !decodeV2(w, r, &req) {
+		return
+	}
+	if req.Task == "" {
+		replyJSONError(w, r, http.StatusBadRequest, "Missing task name")
+		return
+	}
+
+	var (
+		id, status int
+		changeErr  error
+	)
+	err := todo.Update(store, func(l *todo.List) error {
+		l.Add(req.Task)
+		id = l.Items[len(l.Items)-1].ID
+		if req.Parent != 0 {
+			if changeErr = l.SetParent(id, req.Parent); changeErr != nil {
+				status = http.StatusBadRequest
+				return changeErr
+			}
+		}
+		for _, b := range req.BlockedBy {
+			if changeErr = l.Block(id, b); changeErr != nil {
+				status = http.StatusBadRequest
+				return changeErr
+			}
+		}
+
+		status, changeErr = applyPatch(l, id, req.patch())
+		return changeErr
+	})
+	if changeErr != nil {
+		replyJSONError(w, r, status, changeErr.Error())
+		return
+	}
+	if err != nil {
+		replyJSONError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Location", r.URL.Path+"/"+strconv.Itoa(id))
+	w.Header().Set("ETag", itemETag(list, id))
+	replyJSONContent(w, r, http.StatusCreated, &itemResponse{Item: viewOf(list, id)})
+}
+
+// Applies the changes to the item and replies with the changed item
+func updateV2Handler(w http.ResponseWriter, r *http.Request,
+	list *todo.List, id int, p itemPatch, store todo.Storage) {
+
+	var (
+		status    int
+		changeErr error
+	)
+	err := todo.Update(store, func(l *todo.List) error {
+		status, changeErr = applyPatch(l, id, p)
+		return changeErr
+	})
+	if changeErr != nil {
+		replyJSONError(w, r, status, changeErr.Error())
+		return
+	}
+	if err != nil {
+		replyJSONError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", itemETag(list, id))
+	replyJSONContent(w, r, http.StatusOK, &itemResponse{Item: viewOf(list, id)})
+}
+
+// Changes the fields set in the patch and returns the status code of a failure.
+// The list may be partially changed on failure, so it must not be saved,
+// as todo.Update does not save it.
+func applyPatch(list *todo.List, id int, p itemPatch) (int, error) {
+	opts := []todo.Option{}
+	if p.Priority != nil {
+		priority, err := todo.ParsePriority(*p.Priority)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		opts = append(opts, todo.WithPriority(priority))
+	}
+	if p.Due != nil {
+		due, err := parseDue(*p.Due)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		opts = append(opts, todo.WithDue(due))
+	}
+	if p.Tags != nil {
+		opts = append(opts, todo.WithTags(*p.Tags...))
+	}
+	if p.Notes != nil {
+		opts = append(opts, todo.WithNotes(*p.Notes))
+	}
+
+	if len(opts) > 0 {
+		if err := list.SetDetails(id, opts...); err != nil {
+			return http.StatusNotFound, err
+		}
+	}
+	if p.Task != nil && *p.Task != viewOf(list, id).Task {
+		if err := list.Edit(id, *p.Task); err != nil {
+			return http.StatusNotFound, err
+		}
+	}
+
+	if p.Done == nil || *p.Done == viewOf(list, id).Done {
+		return 0, nil
+	}
+
+	var err error
+	if *p.Done {
+		err = list.Complete(id)
+	} else {
+		err = list.Reopen(id)
+	}
+	if errors.Is(err, todo.ErrOpenSubtasks) {
+		return http.StatusConflict, err
+	}
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	return 0, nil
+}
+
+// Patch replacing every field of the item but its parent and blockers
+func (req itemRequest) patch() itemPatch {
+	tags := req.Tags
+	return itemPatch{
+		Task:     &req.Task,
+		Done:     &req.Done,
+		Priority: &req.Priority,
+		Due:      &req.Due,
+		Tags:     &tags,
+		Notes:    &req.Notes,
+	}
+}
+
+// Decodes the JSON body. Unknown fields are rejected, so typos do not go unnoticed.
+// Replies with an error and returns false if the body is invalid.
+func decodeV2(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		message := fmt.Sprintf("Invalid JSON: %s", err)
+		replyJSONError(w, r, http.StatusBadRequest, message)
+		return false
+	}
+
+	return true
+}
+
+// Parses a due date as YYYY-MM-DD or as RFC 3339, as it is returned.
+// An empty date gives no due date.
+func parseDue(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if due, err := time.ParseInLocation(todo.DateFormat, s, time.Local); err == nil {
+		return due, nil
+	}
+
+	due, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: Invalid due date %q", ErrInvalidData, s)
+	}
+
+	return due, nil
+}
+
+// View of the item with the ID, which must be in the list
+func viewOf(list *todo.List, id int) todo.ItemView {
+	idx, _ := list.Index(id)
+	one := todo.List{Items: list.Items[idx : idx+1]}
+	return one.Views()[0]
+}
+
+func replyMethodNotAllowed(w http.ResponseWriter, r *http.Request, allowed ...string) {
+	for _, m := range allowed {
+		w.Header().Add("Allow", m)
+	}
+	replyJSONError(w, r, http.StatusMethodNotAllowed, "Method not supported")
+}
@@ -29,6 +29,7 @@ var rootCmd = &cobra.Command{
 			viper.GetDuration("short"),
 			viper.GetDuration("long"),
 		)
+		config.Task = viper.GetString("task")
 		return rootAction(config)
 	},
 }
@@ -59,11 +60,13 @@ func init() {
 	rootCmd.Flags().DurationP("pomo", "p", 25*time.Minute, "Pomodoro duration")
 	rootCmd.Flags().DurationP("short", "s", 5*time.Minute, "Short break duration")
 	rootCmd.Flags().DurationP("long", "l", 15*time.Minute, "Long break duration")
+	rootCmd.Flags().StringP("task", "t", "", "Todo item the pomodoros are spent on, like 3 or work/3")
 
 	viper.BindPFlag("db", rootCmd.Flags().Lookup("db"))
 	viper.BindPFlag("pomo", rootCmd.Flags().Lookup("pomo"))
 	viper.BindPFlag("short", rootCmd.Flags().Lookup("short"))
 	viper.BindPFlag("long", rootCmd.Flags().Lookup("long"))
+	viper.BindPFlag("task", rootCmd.Flags().Lookup("task"))
 }
 
 // Uses the package "viper" to include configuration management for application.
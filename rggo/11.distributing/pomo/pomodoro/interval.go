@@ -31,6 +31,8 @@ type Interval struct {
 	ActualDuration  time.Duration
 	Category        string
 	State           int
+	// Todo item the interval is spent on, like 3 or work/3. Empty if none.
+	Task string
 }
 
 type Repository interface {
@@ -46,6 +48,8 @@ type Repository interface {
 	Breaks(n int) ([]Interval, error)
 	// Return a daily summary
 	CategorySummary(day time.Time, filter string) (time.Duration, error)
+	// Return the focus time of the pomodoro intervals by task
+	FocusByTask() (map[string]time.Duration, error)
 }
 
 // Errors
@@ -66,6 +70,8 @@ type IntervalConfig struct {
 	PomodoroDuration   time.Duration
 	ShortBreakDuration time.Duration
 	LongBreakDuration  time.Duration
+	// Todo item the pomodoro intervals are spent on
+	Task string
 }
 
 // Instatiate a new IntervalConfig
@@ -197,6 +203,7 @@ func newInterval(config *IntervalConfig) (Interval, error) {
 	switch category {
 	case CategoryPomodoro:
 		i.PlannedDuration = config.PomodoroDuration
+		i.Task = config.Task
 	case CategoryShortBreak:
 		i.PlannedDuration = config.ShortBreakDuration
 	case CategoryLongBreak:
@@ -308,3 +308,42 @@ func TestStart(t *testing.T) {
 		})
 	}
 }
+
+func TestFocusByTask(t *testing.T) {
+	repo, cleanup := getRepo(t)
+	defer cleanup()
+
+	config := pomodoro.NewConfig(repo, 0, 0, 0)
+	config.Task = "work/3"
+
+	i, err := pomodoro.GetInterval(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.Task != "work/3" {
+		t.Errorf("Expected task %q, got %q.", "work/3", i.Task)
+	}
+
+	intervals := []pomodoro.Interval{
+		{Category: pomodoro.CategoryPomodoro, Task: "work/3", ActualDuration: 20 * time.Minute},
+		{Category: pomodoro.CategoryShortBreak, Task: "work/3", ActualDuration: 5 * time.Minute},
+		{Category: pomodoro.CategoryPomodoro, Task: "7", ActualDuration: 25 * time.Minute},
+		{Category: pomodoro.CategoryPomodoro, ActualDuration: 25 * time.Minute},
+	}
+	for _, i := range intervals {
+		i.StartTime = time.Now()
+		if _, err := repo.Create(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	focus, err := repo.FocusByTask()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]time.Duration{"work/3": 20 * time.Minute, "7": 25 * time.Minute}
+	if fmt.Sprint(focus) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v.", expected, focus)
+	}
+}
@@ -111,3 +111,17 @@ func (r *inMemoryRepo) CategorySummary(
 
 	return d, nil
 }
+
+func (r *inMemoryRepo) FocusByTask() (map[string]time.Duration, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	focus := map[string]time.Duration{}
+	for _, i := range r.intervals {
+		if i.Category == pomodoro.CategoryPomodoro && i.Task != "" {
+			focus[i.Task] += i.ActualDuration
+		}
+	}
+
+	return focus, nil
+}
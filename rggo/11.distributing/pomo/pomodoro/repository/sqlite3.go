@@ -21,8 +21,14 @@ const (
 		"actual_duration" INTEGER DEFAULT 0,
 		"category" TEXT NOT NULL,
 		"state" INTEGER DEFAULT 1,
+		"task" TEXT DEFAULT '',
 		PRIMARY KEY ("id")
 	);`
+
+	// Added after the first version of the table
+	addColumnTask string = `ALTER TABLE interval ADD COLUMN "task" TEXT DEFAULT ''`
+
+	intervalColumns = "id, start_time, planned_duration, actual_duration, category, state, task"
 )
 
 type dbRepo struct {
@@ -47,6 +53,19 @@ func NewSQLite3Repo(dbfile string) (*dbRepo, error) {
 		return nil, err
 	}
 
+	// Databases created before intervals had a task get the column
+	var hasTask int
+	err = db.QueryRow(
+		`SELECT count(*) FROM pragma_table_info('interval') WHERE name = 'task'`).Scan(&hasTask)
+	if err != nil {
+		return nil, err
+	}
+	if hasTask == 0 {
+		if _, err := db.Exec(addColumnTask); err != nil {
+			return nil, err
+		}
+	}
+
 	return &dbRepo{
 		db: db,
 	}, nil
@@ -59,7 +78,7 @@ func (r *dbRepo) Create(i pomodoro.Interval) (int64, error) {
 
 	// Prepare INSERT statement
 	insStmt, err := r.db.Prepare(
-		"INSERT INTO interval VALUES(NULL, ?, ?, ?, ?, ?)")
+		"INSERT INTO interval (" + intervalColumns + ") VALUES(NULL, ?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		return 0, err
 	}
@@ -67,7 +86,7 @@ func (r *dbRepo) Create(i pomodoro.Interval) (int64, error) {
 
 	// Exec INSERT statement
 	res, err := insStmt.Exec(
-		i.StartTime, i.PlannedDuration, i.ActualDuration, i.Category, i.State)
+		i.StartTime, i.PlannedDuration, i.ActualDuration, i.Category, i.State, i.Task)
 	if err != nil {
 		return 0, err
 	}
@@ -112,12 +131,12 @@ func (r *dbRepo) ByID(id int64) (pomodoro.Interval, error) {
 	defer r.Unlock()
 
 	// Query DB row based on ID
-	row := r.db.QueryRow("SELECT * FROM interval WHERE id=?", id)
+	row := r.db.QueryRow("SELECT "+intervalColumns+" FROM interval WHERE id=?", id)
 
 	// Parse row into Interval struct
 	i := pomodoro.Interval{}
 	err := row.Scan(
-		&i.ID, &i.StartTime, &i.PlannedDuration, &i.ActualDuration, &i.Category, &i.State)
+		&i.ID, &i.StartTime, &i.PlannedDuration, &i.ActualDuration, &i.Category, &i.State, &i.Task)
 	return i, err
 }
 
@@ -128,9 +147,9 @@ func (r *dbRepo) Last() (pomodoro.Interval, error) {
 
 	// Query and parse last row into Interval struct
 	last := pomodoro.Interval{}
-	err := r.db.QueryRow("SELECT * FROM interval ORDER BY id DESC LIMIT 1").Scan(
+	err := r.db.QueryRow("SELECT "+intervalColumns+" FROM interval ORDER BY id DESC LIMIT 1").Scan(
 		&last.ID, &last.StartTime, &last.PlannedDuration,
-		&last.ActualDuration, &last.Category, &last.State)
+		&last.ActualDuration, &last.Category, &last.State, &last.Task)
 
 	if err == sql.ErrNoRows {
 		return last, pomodoro.ErrNoIntervals
@@ -149,7 +168,7 @@ func (r *dbRepo) Breaks(n int) ([]pomodoro.Interval, error) {
 	defer r.RUnlock()
 
 	// Define SELECT query for breaks
-	stmt := `SELECT * FROM interval WHERE category LIKE '%Break' ORDER BY id DESC LIMIT ?`
+	stmt := `SELECT ` + intervalColumns + ` FROM interval WHERE category LIKE '%Break' ORDER BY id DESC LIMIT ?`
 
 	// Query DB for breaks
 	rows, err := r.db.Query(stmt, n)
@@ -164,7 +183,7 @@ func (r *dbRepo) Breaks(n int) ([]pomodoro.Interval, error) {
 		i := pomodoro.Interval{}
 		err = rows.Scan(
 			&i.ID, &i.StartTime, &i.PlannedDuration,
-			&i.ActualDuration, &i.Category, &i.State)
+			&i.ActualDuration, &i.Category, &i.State, &i.Task)
 		if err != nil {
 			return nil, err
 		}
@@ -203,3 +222,31 @@ func (r *dbRepo) CategorySummary(day time.Time, filter string) (time.Duration, e
 
 	return d, err
 }
+
+// Return the focus time of the pomodoro intervals by task
+func (r *dbRepo) FocusByTask() (map[string]time.Duration, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	rows, err := r.db.Query(
+		`SELECT task, sum(actual_duration)
+		FROM interval
+		WHERE category = ? AND task != ''
+		GROUP BY task`, pomodoro.CategoryPomodoro)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	focus := map[string]time.Duration{}
+	for rows.Next() {
+		var task string
+		var d int64
+		if err := rows.Scan(&task, &d); err != nil {
+			return nil, err
+		}
+		focus[task] = time.Duration(d)
+	}
+
+	return focus, rows.Err()
+}